@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// adminPassword - password of the user "admin" for the /admin pages; without it the admin pages are disabled
+var adminPassword = os.Getenv("ADMIN_PASSWORD")
+
+// adminOnly - requires HTTP basic authentication as the admin
+func adminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminPassword == "" {
+			pageNotFound(w, r)
+			return
+		}
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin" || subtle.ConstantTimeCompare([]byte(password), []byte(adminPassword)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			languageID := errorLanguage(r)
+			renderError(w, r, http.StatusUnauthorized, languageID, errorText(languageID, "unauthorized"), errorText(languageID, "login"), nil)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// AdminIASTHandler - Devanagari and IAST lines which disagree: /admin/lt/iast
+func AdminIASTHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+
+	fp := path.Join("templates", "admin_iast.html")
+	tmpl, err := template.ParseFiles(fp)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	// construct mismatches list
+	mismatches := checkIAST(book)
+	var mismatchesList []template.HTML
+	for _, mismatch := range mismatches {
+		var chapterNum, verseNum int
+		fmt.Sscanf(mismatch.Location, "%d.%d", &chapterNum, &verseNum)
+		verseURL, err := verseURL(vars["language"], book, chapterNum, verseNum)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+
+		locationHref := template.HTML(fmt.Sprintf(`<a href="%s">%s</a>`, verseURL.String(), mismatch.Location))
+		lines := template.HTML(template.HTMLEscapeString(mismatch.Devanagari) + "<br>" + template.HTMLEscapeString(mismatch.IAST))
+		var issues []string
+		for _, issue := range mismatch.Issues {
+			issues = append(issues, template.HTMLEscapeString(issue))
+		}
+		mismatchesList = append(mismatchesList, "<td valign=\"top\">"+locationHref+"</td><td>"+lines+"</td><td>"+mismatch.DiffHTML()+"</td><td>"+template.HTML(strings.Join(issues, "<br>"))+"</td>")
+	}
+
+	data := map[string]interface{}{
+		"languageId": vars["language"],
+		"title":      localised(bookTitles, vars["language"]),
+		"total":      strconv.Itoa(len(mismatches)),
+		"mismatches": mismatchesList,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		serverError(w, r, err)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// Parameters of the alignment
+const (
+	alignFrame       = 0.01 // seconds of an energy frame
+	alignMinPause    = 0.12 // seconds of silence to be a pause between words
+	alignMinWord     = 0.05 // seconds a word lasts at least
+	alignValleyDip   = 4.0  // dB an energy valley lies below the peaks around it to be a word boundary
+	alignMaxDepth    = 30.0 // dB of a valley as distinct as a pause
+	alignDepthWeight = 2.0  // worth of a boundary on a pause against a word lasting e times its estimated duration
+)
+
+// recordingNameRe - recording file names, as on the media server: 2-13.wav, 1-16.wav for the group 1.16-18
+// (or 1-16-18.wav)
+var recordingNameRe = regexp.MustCompile(`^(\d{1,2})-(\d{1,2}(?:-\d{1,2})?)\.wav$`)
+
+// Pause - silence in a recording, from Start to End seconds
+type Pause struct {
+	Start, End float64
+}
+
+// Valley - dip of energy within speech at Time seconds, Depth dB below the lower of the peaks around it
+type Valley struct {
+	Time, Depth float64
+}
+
+// Features - what the alignment needs to know of a recording
+type Features struct {
+	SpeechStart, SpeechEnd float64  // seconds of the first and the last sound
+	Pauses                 []Pause  // silences between SpeechStart and SpeechEnd
+	Valleys                []Valley // dips within speech: between words, but also between syllables
+}
+
+// analyseRecording - finds speech, pauses and energy valleys in frames of alignFrame seconds. Silence lies below a
+// threshold between the noise floor and the loudness of the speech, both taken from the distribution of frame energies.
+func analyseRecording(rec *Recording) Features {
+	frameSize := int(alignFrame * float64(rec.SampleRate))
+	var energies []float64 // dB
+	for pos := 0; pos+frameSize <= len(rec.Samples); pos += frameSize {
+		sum := 0.0
+		for _, sample := range rec.Samples[pos : pos+frameSize] {
+			sum += sample * sample
+		}
+		energies = append(energies, 10*math.Log10(sum/float64(frameSize)+1e-12))
+	}
+	if len(energies) == 0 {
+		return Features{}
+	}
+
+	sorted := append([]float64{}, energies...)
+	sort.Float64s(sorted)
+	floor, loud := sorted[len(sorted)/10], sorted[len(sorted)*95/100]
+	threshold := floor + (loud-floor)*0.3
+
+	first, last := -1, -1
+	for i, energy := range energies {
+		if energy > threshold {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return Features{}
+	}
+	features := Features{SpeechStart: float64(first) * alignFrame, SpeechEnd: float64(last+1) * alignFrame}
+
+	// pauses: runs of silent frames within the speech
+	for i := first; i <= last; {
+		if energies[i] > threshold {
+			i++
+			continue
+		}
+		j := i
+		for j <= last && energies[j] <= threshold {
+			j++
+		}
+		if float64(j-i)*alignFrame >= alignMinPause {
+			features.Pauses = append(features.Pauses, Pause{float64(i) * alignFrame, float64(j) * alignFrame})
+		}
+		i = j
+	}
+
+	// valleys: local minima of the smoothed energy well below the peaks on both sides, including silences too short to be pauses
+	smoothed := make([]float64, len(energies))
+	for i := range energies {
+		from, to := maxInt(i-2, 0), minInt(i+3, len(energies))
+		for _, energy := range energies[from:to] {
+			smoothed[i] += energy / float64(to-from)
+		}
+	}
+	const reach = 10 // frames on each side
+	for i := first + 1; i < last; i++ {
+		if smoothed[i] > smoothed[i-1] || smoothed[i] > smoothed[i+1] {
+			continue
+		}
+		leftPeak, rightPeak := smoothed[i], smoothed[i]
+		for _, energy := range smoothed[maxInt(i-reach, first):i] {
+			leftPeak = math.Max(leftPeak, energy)
+		}
+		for _, energy := range smoothed[i+1 : minInt(i+reach+1, last+1)] {
+			rightPeak = math.Max(rightPeak, energy)
+		}
+		if depth := math.Min(leftPeak, rightPeak) - smoothed[i]; depth >= alignValleyDip {
+			features.Valleys = append(features.Valleys, Valley{(float64(i) + 0.5) * alignFrame, depth})
+		}
+	}
+	return features
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// alignWords - word timings of the words in the recording. The ends of the lines - dandas, or just line breaks
+// in IAST - take the pauses nearest to their estimated places; between them alignSegment chooses the boundaries.
+func alignWords(words [][]Word, parse func(string) []translit.Token, features Features) []float32 {
+	weights := wordWeights(words, parse)
+	if len(weights) == 0 || features.SpeechEnd <= features.SpeechStart {
+		return nil
+	}
+	isDanda := make([]bool, len(weights))
+	var lineEnds []int // boundaries after the last word of a line
+	for lineIdx, lineWords := range words {
+		last := -1
+		for _, word := range lineWords {
+			if word.Index >= 0 {
+				isDanda[word.Index] = word.Text == "।"
+				last = word.Index
+			}
+		}
+		if last >= 0 && lineIdx < len(words)-1 {
+			lineEnds = append(lineEnds, last+1)
+		}
+	}
+
+	// estimate over the whole speech
+	starts := estimateStarts(weights, 0, len(weights), features.SpeechStart, features.SpeechEnd)
+
+	// a danda spans its pause, a line break without one ends the pause
+	anchors := map[int]bool{0: true, len(weights): true}
+	usedPauses := map[int]bool{}
+	after := features.SpeechStart // pauses are taken in order
+	for _, boundary := range lineEnds {
+		danda := boundary > 0 && isDanda[boundary-1]
+		expected := starts[boundary]
+		if danda {
+			expected = (starts[boundary-1] + starts[boundary]) / 2
+		}
+		nearest := -1
+		for p, pause := range features.Pauses {
+			if pause.Start > after && (nearest < 0 || math.Abs((pause.Start+pause.End)/2-expected) < math.Abs((features.Pauses[nearest].Start+features.Pauses[nearest].End)/2-expected)) {
+				nearest = p
+			}
+		}
+		if nearest < 0 {
+			continue
+		}
+		pause := features.Pauses[nearest]
+		usedPauses[nearest] = true
+		after = pause.End
+		if danda {
+			starts[boundary-1], anchors[boundary-1] = pause.Start, true
+		}
+		starts[boundary], anchors[boundary] = pause.End, true
+	}
+
+	// the other pauses and the valleys are the candidate boundaries of the words between anchors
+	var candidates []Valley
+	for p, pause := range features.Pauses {
+		if !usedPauses[p] {
+			candidates = append(candidates, Valley{pause.End, math.Inf(1)}) // the next word starts when the sound does
+		}
+	}
+	candidates = append(candidates, features.Valleys...)
+	for from := 0; from < len(weights); {
+		to := from + 1
+		for !anchors[to] {
+			to++
+		}
+		alignSegment(starts, weights, from, to, candidates)
+		from = to
+	}
+
+	timings := make([]float32, len(starts))
+	for i, start := range starts {
+		timings[i] = float32(math.Floor(start*100+0.5) / 100)
+	}
+	return timings
+}
+
+// estimateStarts - start times of the words from..to sharing the time between start and end by their weights,
+// followed by end
+func estimateStarts(weights []int, from, to int, start, end float64) []float64 {
+	total := 0
+	for _, weight := range weights[from:to] {
+		total += weight
+	}
+	starts := make([]float64, 0, to-from+1)
+	elapsed := 0
+	for _, weight := range weights[from:to] {
+		starts = append(starts, start+float64(elapsed)/float64(total)*(end-start))
+		elapsed += weight
+	}
+	return append(starts, end)
+}
+
+// alignSegment - sets the starts of the words from+1..to-1 between the fixed starts[from] and starts[to]. Among the
+// candidates within the segment and the estimated starts it chooses the boundaries that keep the words closest to
+// their estimated durations and fall on the most distinct pauses and valleys.
+func alignSegment(starts []float64, weights []int, from, to int, candidates []Valley) {
+	if to-from < 2 {
+		return
+	}
+	start, end := starts[from], starts[to]
+	estimated := estimateStarts(weights, from, to, start, end)
+
+	points := []Valley{{start, 0}}
+	for _, t := range estimated[1 : len(estimated)-1] {
+		points = append(points, Valley{t, 0})
+	}
+	for _, candidate := range candidates {
+		if candidate.Time > start+alignMinWord && candidate.Time < end-alignMinWord {
+			points = append(points, candidate)
+		}
+	}
+	points = append(points, Valley{end, 0})
+	sort.SliceStable(points[1:len(points)-1], func(i, j int) bool { return points[i+1].Time < points[j+1].Time })
+
+	// cost[k][p]: the best cost of the first k words with the k-th one ending at points[p]
+	words := to - from
+	cost := make([][]float64, words+1)
+	previous := make([][]int, words+1)
+	for k := range cost {
+		cost[k] = make([]float64, len(points))
+		previous[k] = make([]int, len(points))
+		for p := range cost[k] {
+			cost[k][p] = math.Inf(1)
+		}
+	}
+	cost[0][0] = 0
+	for k := 1; k <= words; k++ {
+		expected := estimated[k] - estimated[k-1]
+		for p := k; p < len(points); p++ {
+			if (k == words) != (p == len(points)-1) {
+				continue // the last word and only the last one ends at the end of the segment
+			}
+			reward := 0.0
+			if k < words {
+				reward = alignDepthWeight * math.Min(points[p].Depth, alignMaxDepth) / alignMaxDepth
+			}
+			for q := k - 1; q < p; q++ {
+				duration := points[p].Time - points[q].Time
+				if math.IsInf(cost[k-1][q], 1) || duration < alignMinWord {
+					continue
+				}
+				deviation := math.Log(duration / expected)
+				if c := cost[k-1][q] + deviation*deviation - reward; c < cost[k][p] {
+					cost[k][p], previous[k][p] = c, q
+				}
+			}
+		}
+	}
+	if math.IsInf(cost[words][len(points)-1], 1) {
+		return // too short a segment for its words: keep the estimate
+	}
+	for k, p := words, len(points)-1; k > 1; k-- {
+		p = previous[k][p]
+		starts[from+k-1] = points[p].Time
+	}
+}
+
+// groupWords - words of all verses of a group numbered through, and where the words of every verse start
+func groupWords(verses []Verse, lines func(Verse) [][]Word) ([][]Word, []int) {
+	var words [][]Word
+	var offsets []int
+	offset := 0
+	for _, verse := range verses {
+		offsets = append(offsets, offset)
+		count := wordCount(lines(verse))
+		for _, lineWords := range lines(verse) {
+			numbered := append([]Word{}, lineWords...)
+			for i := range numbered {
+				if numbered[i].Index >= 0 {
+					numbered[i].Index += offset
+				}
+			}
+			words = append(words, numbered)
+		}
+		offset += count
+	}
+	return words, offsets
+}
+
+// alignReport - words with their recorded and proposed start times, for a human to review
+func alignReport(location, track string, words [][]Word, old, proposed []float32) string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s %s: %d timings", location, track, len(proposed))
+	if len(old) > 0 {
+		fmt.Fprintf(&out, ", replacing %d", len(old))
+	}
+	out.WriteString("\n")
+	for _, lineWords := range words {
+		for _, word := range lineWords {
+			if word.Index < 0 {
+				continue
+			}
+			// pad by letters, not bytes, to keep the columns of Devanagari and IAST
+			text := word.Text + strings.Repeat(" ", maxInt(24-utf8.RuneCountInString(word.Text), 0))
+			if word.Index < len(old) {
+				fmt.Fprintf(&out, "  %s %7.2f -> %7.2f  (%+.2f)\n", text, old[word.Index], proposed[word.Index], proposed[word.Index]-old[word.Index])
+			} else {
+				fmt.Fprintf(&out, "  %s %7s -> %7.2f\n", text, "", proposed[word.Index])
+			}
+		}
+	}
+	fmt.Fprintf(&out, "  %-24s %7s -> %7.2f\n", "(end)", "", proposed[len(proposed)-1])
+	return out.String()
+}
+
+// alignCommand - "align [-write] [-recitation id] [-file file.json] recording.wav..." subcommand: proposes word timings
+// of the verses recorded in the files named as on the media server (2-13.wav, 1-16.wav) and prints them for review;
+// with -write they replace the recitation's timings in the catalogue. Exit code is 0, 1 if a recording could not be
+// aligned, 2 on a load error.
+func alignCommand(args []string) int {
+	flags := flag.NewFlagSet("align", flag.ExitOnError)
+	write := flags.Bool("write", false, "write the proposed timings into "+catalogueName)
+	recitationID := flags.String("recitation", "", "ID of the recitation in "+catalogueName+", the first one by default")
+	file := flags.String("file", "", "corpus file, the "+defaultLangID+" corpus in "+textsDir+" by default")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s align [-write] [-recitation id] [-file file.json] chapter-verse.wav...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if *file == "" {
+		corpusFiles, err := discoverCorpora(textsDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*file = corpusFiles[defaultLangID]
+	}
+	book, err := loadBook(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	catalogue, err := loadCatalogue(catalogueFile())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	recitation := catalogue.Recitations[0]
+	if *recitationID != "" {
+		if recitation = catalogue.Recitation(*recitationID); recitation == nil {
+			fmt.Fprintf(os.Stderr, "No recitation %q in %s\n", *recitationID, catalogueFile())
+			return 2
+		}
+	}
+	if recitation.Timings == nil {
+		recitation.Timings = map[string]map[string][]float32{}
+	}
+
+	exitCode := 0
+	for _, recordingFile := range flags.Args() {
+		match := recordingNameRe.FindStringSubmatch(filepath.Base(recordingFile))
+		if match == nil {
+			fmt.Fprintf(os.Stderr, "%s: not named chapter-verse.wav\n", recordingFile)
+			exitCode = 1
+			continue
+		}
+		chapterNum, _ := strconv.Atoi(match[1])
+		verseNum, lastVerseNum := parseVerseRef(match[2])
+		if chapterNum < 1 || chapterNum > len(book.Chapters) || verseNum < 1 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+			fmt.Fprintf(os.Stderr, "%s: verse %s.%s does not exist!\n", recordingFile, match[1], match[2])
+			exitCode = 1
+			continue
+		}
+		verses := book.Chapters[chapterNum-1].Group(verseNum)
+		if verseNum != verses[0].From || (lastVerseNum != verseNum && lastVerseNum != verses[0].To) {
+			fmt.Fprintf(os.Stderr, "%s: the recording must cover the verse group %d.%s\n", recordingFile, chapterNum, verses[0].Ref())
+			exitCode = 1
+			continue
+		}
+
+		recording, err := readWAV(recordingFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			continue
+		}
+		features := analyseRecording(recording)
+		fmt.Printf("%s: %.2fs, speech %.2f-%.2f, %d pauses, %d valleys\n", recordingFile, recording.Duration(), features.SpeechStart, features.SpeechEnd, len(features.Pauses), len(features.Valleys))
+
+		for _, track := range timingTracks {
+			words, offsets := groupWords(verses, track.Words)
+			timings := alignWords(words, track.Parse, features)
+			if timings == nil {
+				fmt.Fprintf(os.Stderr, "%s: no speech or no %s words to align\n", recordingFile, track.ID)
+				exitCode = 1
+				continue
+			}
+			// every verse of a group gets its own words' timings, in seconds of the group's recording
+			for v, verse := range verses {
+				count := wordCount(track.Words(verse))
+				verseTimings := timings[offsets[v] : offsets[v]+count+1]
+				location := fmt.Sprintf("%d.%d", chapterNum, verse.Num)
+				fmt.Print(alignReport(location, track.ID, track.Words(verse), recitation.VerseTimings(chapterNum, verse.Num, track.ID), verseTimings))
+				if recitation.Timings[location] == nil {
+					recitation.Timings[location] = map[string][]float32{}
+				}
+				recitation.Timings[location][track.ID] = verseTimings
+			}
+		}
+	}
+
+	if *write {
+		if err := saveCatalogue(catalogue, catalogueFile()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s not written: %s\n", catalogueFile(), err)
+			return 2
+		}
+		fmt.Printf("%s written\n", catalogueFile())
+	}
+	return exitCode
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+	"github.com/gorilla/mux"
+)
+
+// apiRef - reference to a neighbouring chapter or verse in API responses
+type apiRef struct {
+	Chapter int    `json:"chapter"`
+	Verse   int    `json:"verse,omitempty"`
+	URL     string `json:"url"`
+}
+
+// apiChapterSummary - entry of the chapters list
+type apiChapterSummary struct {
+	Num    int    `json:"num"`
+	Name   string `json:"name"`
+	Verses int    `json:"verses"`
+	URL    string `json:"url"`
+}
+
+// apiChapter - chapter with all its verses
+type apiChapter struct {
+	Num    int           `json:"num"`
+	Name   string        `json:"name"`
+	Prev   *apiRef       `json:"prev"`
+	Next   *apiRef       `json:"next"`
+	Verses []interface{} `json:"verses"` // apiVerse, possibly reduced to the selected fields
+}
+
+// apiSynonym - word-for-word translation of a Sanskrit word
+type apiSynonym struct {
+	Sanskrit    string        `json:"sanskrit"`
+	Translation template.HTML `json:"translation"`
+}
+
+// apiVerse - verse; Translation and Purport are the ones of the whole verse group From..To
+type apiVerse struct {
+	Chapter               int             `json:"chapter"`
+	Num                   int             `json:"num"`
+	From                  int             `json:"from"`
+	To                    int             `json:"to"`
+	Devanagari            []string        `json:"devanagari"`
+	DevanagariWordTimings []float32       `json:"devanagariWordTimings,omitempty"` // of the Recitation
+	IAST                  []string        `json:"iast"`
+	IASTWordTimings       []float32       `json:"iastWordTimings,omitempty"`
+	Synonyms              []apiSynonym    `json:"synonyms"`
+	Translation           template.HTML   `json:"translation"`
+	Purport               []template.HTML `json:"purport"`
+	Prev                  *apiRef         `json:"prev"`
+	Next                  *apiRef         `json:"next"`
+	Script                string          `json:"script,omitempty"`          // ?script= scheme of Transliteration
+	Transliteration       []string        `json:"transliteration,omitempty"` // lines in the Script
+	Recitation            string          `json:"recitation,omitempty"`      // ?recitation= or the first one with a recording of the verse
+	Audio                 []apiAudio      `json:"audio,omitempty"`           // recording of the verse group in the Recitation
+}
+
+// apiAudio - recording file of a verse group
+type apiAudio struct {
+	Format string `json:"format"`
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+}
+
+// apiRecitation - entry of the recitations list
+type apiRecitation struct {
+	ID       string   `json:"id"`
+	Reciter  string   `json:"reciter"`
+	Language string   `json:"language"`
+	Style    string   `json:"style"`
+	Formats  []string `json:"formats"`
+	Verses   []string `json:"verses"` // recorded chapters, verses and ranges; all if null
+}
+
+// apiKeyFields - fields of a verse returned regardless of ?fields= selection, if present
+var apiKeyFields = []string{"chapter", "num", "from", "to", "prev", "next", "script", "transliteration", "recitation"}
+
+// writeJSON - writes v as a JSON response; HTML in the texts is not escaped
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeJSONError - writes {"error": message} with the given HTTP status
+func writeJSONError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// apiServerError - logs the error with the request and writes a 500 error without its details
+func apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %s", r.Method, r.URL, err)
+	writeJSONError(w, http.StatusInternalServerError, "internal server error")
+}
+
+// apiChapterRef - reference to a chapter, nil for chapter 0 (no chapter)
+func apiChapterRef(languageID string, chapterNum int) (*apiRef, error) {
+	if chapterNum == 0 {
+		return nil, nil
+	}
+	url, err := router.Get("apiChapter").URL("language", languageID, "chapter", strconv.Itoa(chapterNum))
+	if err != nil {
+		return nil, err
+	}
+	return &apiRef{Chapter: chapterNum, URL: url.String()}, nil
+}
+
+// apiVerseRef - reference to a verse, nil for [0, 0] (no verse)
+func apiVerseRef(languageID string, verseRef [2]int) (*apiRef, error) {
+	if verseRef[1] == 0 {
+		return nil, nil
+	}
+	url, err := router.Get("apiChapterVerse").URL("language", languageID, "chapter", strconv.Itoa(verseRef[0]), "verse", strconv.Itoa(verseRef[1]))
+	if err != nil {
+		return nil, err
+	}
+	return &apiRef{Chapter: verseRef[0], Verse: verseRef[1], URL: url.String()}, nil
+}
+
+// apiScheme - scheme of the ?script= parameter, "" if none
+func apiScheme(r *http.Request) (translit.Scheme, error) {
+	script := r.URL.Query().Get("script")
+	if script == "" {
+		return "", nil
+	}
+	scheme, ok := translit.ParseScheme(script)
+	if !ok {
+		return "", fmt.Errorf("unknown script %q", script)
+	}
+	return scheme, nil
+}
+
+// apiRecitationID - recitation ID of the ?recitation= parameter, "" if none
+func apiRecitationID(r *http.Request) (string, error) {
+	id := r.URL.Query().Get("recitation")
+	if id != "" && Recitations().Recitation(id) == nil {
+		return "", fmt.Errorf("unknown recitation %q", id)
+	}
+	return id, nil
+}
+
+// newAPIVerse - API representation of a verse, transliterated to the scheme unless it is "", with the audio
+// and word timings of the recitation or the first one with a recording of the verse if recitationID is ""
+func newAPIVerse(languageID string, chapter Chapter, verse Verse, scheme translit.Scheme, recitationID string) (apiVerse, error) {
+	prev, err := apiVerseRef(languageID, verse.PrevVerse)
+	if err != nil {
+		return apiVerse{}, err
+	}
+	next, err := apiVerseRef(languageID, verse.NextVerse)
+	if err != nil {
+		return apiVerse{}, err
+	}
+	carrier := chapter.Verses[verse.To-1]
+	apiVerse := apiVerse{
+		Chapter:     chapter.Num,
+		Num:         verse.Num,
+		From:        verse.From,
+		To:          verse.To,
+		Devanagari:  verse.Devanagari,
+		IAST:        verse.IAST,
+		Synonyms:    []apiSynonym{},
+		Translation: carrier.Translation,
+		Purport:     carrier.Purport,
+		Prev:        prev,
+		Next:        next,
+	}
+	if recitation := Recitations().Select(recitationID, chapter.Num, verse.From); recitation.Has(chapter.Num, verse.From) {
+		apiVerse.Recitation = recitation.ID
+		apiVerse.DevanagariWordTimings = recitation.VerseTimings(chapter.Num, verse.Num, "devanagari")
+		apiVerse.IASTWordTimings = recitation.VerseTimings(chapter.Num, verse.Num, "iast")
+		for _, format := range recitation.VerseFormats(chapter.Num, verse.From) {
+			apiVerse.Audio = append(apiVerse.Audio, apiAudio{Format: format, Type: mediaTypes[format], URL: recitation.AudioURL(chapter.Num, verse.From, format)})
+		}
+	}
+	for i, sanskrit := range verse.SynonymsSanskrit {
+		apiVerse.Synonyms = append(apiVerse.Synonyms, apiSynonym{Sanskrit: sanskrit, Translation: verse.SynonymsTranslation[i]})
+	}
+	if scheme != "" {
+		apiVerse.Script = string(scheme)
+		apiVerse.Transliteration = []string{}
+		if scheme.IsRoman() {
+			// from the IAST lines to keep their word division, as on the verse page
+			for _, line := range verse.IAST {
+				apiVerse.Transliteration = append(apiVerse.Transliteration, translit.FromIAST(line, scheme))
+			}
+		} else {
+			for _, line := range verse.Devanagari {
+				apiVerse.Transliteration = append(apiVerse.Transliteration, translit.FromDevanagari(line, scheme))
+			}
+		}
+	}
+	return apiVerse, nil
+}
+
+// selectFields - reduces v to the comma separated JSON fields (plus the key fields); all fields if fields is empty
+func selectFields(v interface{}, fields string) (interface{}, error) {
+	if fields == "" {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := map[string]json.RawMessage{}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		} else if field != "" {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	for _, field := range apiKeyFields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+// APIChaptersHandler - list of chapters: /api/v1/lt/chapters
+func APIChaptersHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+
+	chapters := []apiChapterSummary{}
+	for _, chapter := range book.Chapters {
+		ref, err := apiChapterRef(vars["language"], chapter.Num)
+		if err != nil {
+			apiServerError(w, r, err)
+			return
+		}
+		chapters = append(chapters, apiChapterSummary{
+			Num:    chapter.Num,
+			Name:   chapter.Name,
+			Verses: len(chapter.Verses),
+			URL:    ref.URL,
+		})
+	}
+	writeJSON(w, http.StatusOK, chapters)
+}
+
+// APIChapterHandler - chapter with its verses: /api/v1/lt/chapters/2?fields=translation&script=hk
+func APIChapterHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+	chapterNum, _ := strconv.Atoi(vars["chapter"])
+
+	if chapterNum < 1 || chapterNum > len(book.Chapters) {
+		writeJSONError(w, http.StatusNotFound, "chapter %v does not exist", vars["chapter"])
+		return
+	}
+	chapter := book.Chapters[chapterNum-1]
+
+	prev, err := apiChapterRef(vars["language"], chapter.PrevChapter)
+	if err != nil {
+		apiServerError(w, r, err)
+		return
+	}
+	next, err := apiChapterRef(vars["language"], chapter.NextChapter)
+	if err != nil {
+		apiServerError(w, r, err)
+		return
+	}
+	apiChapter := apiChapter{
+		Num:    chapter.Num,
+		Name:   chapter.Name,
+		Prev:   prev,
+		Next:   next,
+		Verses: []interface{}{},
+	}
+	scheme, err := apiScheme(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	recitationID, err := apiRecitationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	for _, verse := range chapter.Verses {
+		fullVerse, err := newAPIVerse(vars["language"], chapter, verse, scheme, recitationID)
+		if err != nil {
+			apiServerError(w, r, err)
+			return
+		}
+		apiVerse, err := selectFields(fullVerse, r.URL.Query().Get("fields"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		apiChapter.Verses = append(apiChapter.Verses, apiVerse)
+	}
+	writeJSON(w, http.StatusOK, apiChapter)
+}
+
+// APIChapterVerseHandler - single verse: /api/v1/lt/chapters/2/verses/13?fields=translation,iast&script=hk&recitation=1
+func APIChapterVerseHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+	chapterNum, _ := strconv.Atoi(vars["chapter"])
+	verseNum, _ := strconv.Atoi(vars["verse"])
+
+	if chapterNum < 1 || chapterNum > len(book.Chapters) {
+		writeJSONError(w, http.StatusNotFound, "chapter %v does not exist", vars["chapter"])
+		return
+	}
+	chapter := book.Chapters[chapterNum-1]
+	if verseNum < 1 || verseNum > len(chapter.Verses) {
+		writeJSONError(w, http.StatusNotFound, "verse %v.%v does not exist", vars["chapter"], vars["verse"])
+		return
+	}
+
+	scheme, err := apiScheme(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	recitationID, err := apiRecitationID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	fullVerse, err := newAPIVerse(vars["language"], chapter, chapter.Verses[verseNum-1], scheme, recitationID)
+	if err != nil {
+		apiServerError(w, r, err)
+		return
+	}
+	apiVerse, err := selectFields(fullVerse, r.URL.Query().Get("fields"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiVerse)
+}
+
+// APIRecitationsHandler - recitations of the book: /api/v1/recitations
+func APIRecitationsHandler(w http.ResponseWriter, r *http.Request) {
+	recitations := []apiRecitation{}
+	for _, recitation := range Recitations().Recitations {
+		recitations = append(recitations, apiRecitation{
+			ID:       recitation.ID,
+			Reciter:  recitation.Reciter,
+			Language: recitation.Language,
+			Style:    recitation.Style,
+			Formats:  recitation.Formats,
+			Verses:   recitation.Verses,
+		})
+	}
+	writeJSON(w, http.StatusOK, recitations)
+}
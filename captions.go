@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Cue - a caption shown from Start to End seconds of the recitation
+type Cue struct {
+	Start, End float32
+	Text       string
+	Words      []Word // words of a line cue, for the WebVTT karaoke timestamps
+}
+
+// captionCues - cues of the timed words, either one for every word or one for every line ("line")
+func captionCues(words [][]Word, unit string) []Cue {
+	var cues []Cue
+	for _, lineWords := range words {
+		var timed []Word
+		for _, word := range lineWords {
+			// a danda takes no time of its own to be shown
+			if word.Timed && word.End > word.Start {
+				timed = append(timed, word)
+			}
+		}
+		if len(timed) == 0 {
+			continue
+		}
+		if unit == "line" {
+			var texts []string
+			for _, word := range timed {
+				texts = append(texts, word.Text)
+			}
+			cues = append(cues, Cue{Start: timed[0].Start, End: timed[len(timed)-1].End, Text: strings.Join(texts, " "), Words: timed})
+		} else {
+			for _, word := range timed {
+				cues = append(cues, Cue{Start: word.Start, End: word.End, Text: word.Text})
+			}
+		}
+	}
+	return cues
+}
+
+// cueTime - seconds as hours:minutes:seconds with milliseconds after the separator: "." in WebVTT, "," in SRT
+func cueTime(seconds float32, separator string) string {
+	ms := int(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+// writeWebVTT - cues as a WebVTT file; line cues mark the start of every following word for karaoke styling
+func writeWebVTT(cues []Cue) []byte {
+	var out bytes.Buffer
+	out.WriteString("WEBVTT\n")
+	for i, cue := range cues {
+		fmt.Fprintf(&out, "\n%d\n%s --> %s\n", i+1, cueTime(cue.Start, "."), cueTime(cue.End, "."))
+		if len(cue.Words) == 0 {
+			out.WriteString(vttEscaper.Replace(cue.Text))
+		}
+		for j, word := range cue.Words {
+			if j > 0 {
+				fmt.Fprintf(&out, " <%s>", cueTime(word.Start, "."))
+			}
+			out.WriteString(vttEscaper.Replace(word.Text))
+		}
+		out.WriteString("\n")
+	}
+	return out.Bytes()
+}
+
+// vttEscaper - escapes the characters with a meaning in WebVTT cue text
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// writeSRT - cues as a SubRip file
+func writeSRT(cues []Cue) []byte {
+	var out bytes.Buffer
+	for i, cue := range cues {
+		fmt.Fprintf(&out, "%d\n%s --> %s\n%s\n\n", i+1, cueTime(cue.Start, ","), cueTime(cue.End, ","), cue.Text)
+	}
+	return out.Bytes()
+}
+
+// captionsURL - URL of the captions of a verse group in the format (vtt, srt) for the recitation and track
+func captionsURL(languageID string, book *Book, chapterNum, verseNum int, format, recitationID, track string) (*url.URL, error) {
+	verse := book.Chapters[chapterNum-1].Verses[verseNum-1]
+	captionsURL, err := router.Get("langChapterVerseCaptions").URL("language", languageID, "chapter", strconv.Itoa(chapterNum), "verse", verse.Ref(), "format", format)
+	if err != nil {
+		return nil, err
+	}
+	captionsURL.RawQuery = url.Values{"recitation": {recitationID}, "track": {track}}.Encode()
+	return captionsURL, nil
+}
+
+// LangChapterVerseCaptionsHandler - captions of a recitation from its word timings: /lt/2/13.vtt?recitation=1&track=iast&cues=word
+func LangChapterVerseCaptionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+	chapterNum, _ := strconv.Atoi(vars["chapter"])
+	verseNum, lastVerseNum := parseVerseRef(vars["verse"])
+
+	if chapterNum == 0 || chapterNum > len(book.Chapters) {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "chapter"), vars["chapter"]), verseSuggestions(vars["language"], book, chapterNum, verseNum))
+		return
+	}
+	if verseNum == 0 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "verse"), vars["chapter"], vars["verse"]), verseSuggestions(vars["language"], book, chapterNum, verseNum))
+		return
+	}
+
+	trackID := r.URL.Query().Get("track")
+	if trackID == "" {
+		trackID = "iast"
+	}
+	track, ok := timingTrack(trackID)
+	if !ok {
+		badRequest(w, r, fmt.Sprintf(errorText(vars["language"], "track"), trackID))
+		return
+	}
+	unit := r.URL.Query().Get("cues")
+	if unit == "" {
+		unit = "line"
+	} else if unit != "line" && unit != "word" {
+		badRequest(w, r, fmt.Sprintf(errorText(vars["language"], "cues"), unit))
+		return
+	}
+
+	// Captions belong to the recording of the whole group, like the verse page
+	verses := book.Chapters[chapterNum-1].Group(verseNum)
+	recitation := Recitations().Select(r.URL.Query().Get("recitation"), chapterNum, verses[0].From)
+	if verseNum != verses[0].From || lastVerseNum != verses[0].To {
+		url, err := captionsURL(vars["language"], book, chapterNum, verseNum, vars["format"], recitation.ID, trackID)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		url.RawQuery = r.URL.RawQuery
+		http.Redirect(w, r, url.String(), 301)
+		return
+	}
+
+	var cues []Cue
+	for _, verseWords := range recitation.GroupWords(chapterNum, verses, track) {
+		cues = append(cues, captionCues(verseWords, unit)...)
+	}
+	if len(cues) == 0 {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "timings"), vars["chapter"], vars["verse"], recitation.ID, trackID), nil)
+		return
+	}
+
+	if vars["format"] == "srt" {
+		w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+		w.Write(writeSRT(cues))
+	} else {
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		w.Write(writeWebVTT(cues))
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestCueTime(t *testing.T) {
+	tests := []struct {
+		seconds   float32
+		separator string
+		want      string
+	}{
+		{0, ".", "00:00:00.000"},
+		{1.5, ".", "00:00:01.500"},
+		{1.5, ",", "00:00:01,500"},
+		{59.9996, ".", "00:01:00.000"},
+		{61.25, ",", "00:01:01,250"},
+		{3725.042, ".", "01:02:05.042"},
+	}
+	for _, test := range tests {
+		if got := cueTime(test.seconds, test.separator); got != test.want {
+			t.Errorf("cueTime(%v, %q) = %q, want %q", test.seconds, test.separator, got, test.want)
+		}
+	}
+}
+
+// captionWords - two timed lines, the first one ending with an untimed danda
+var captionWords = [][]Word{
+	{
+		{Index: 0, Text: "dharma-kṣetre", Start: 0, End: 1.2, Timed: true},
+		{Index: 1, Text: "kuru-kṣetre", Start: 1.2, End: 2.5, Timed: true},
+		{Index: 2, Text: "।", Start: 2.5, End: 2.5, Timed: true},
+	},
+	{
+		{Index: 3, Text: "samavetā", Start: 2.5, End: 3.75, Timed: true},
+		{Index: 4, Text: "<yuyutsavaḥ>", Start: 3.75, End: 5, Timed: true},
+	},
+}
+
+func TestCaptionCues(t *testing.T) {
+	lines := captionCues(captionWords, "line")
+	if len(lines) != 2 {
+		t.Fatalf("%d line cues, want 2: %+v", len(lines), lines)
+	}
+	if lines[0].Text != "dharma-kṣetre kuru-kṣetre" || lines[0].Start != 0 || lines[0].End != 2.5 {
+		t.Errorf("first line cue %+v", lines[0])
+	}
+	if words := captionCues(captionWords, "word"); len(words) != 4 {
+		t.Errorf("%d word cues, want 4 without the danda: %+v", len(words), words)
+	}
+	untimed := [][]Word{{{Index: 0, Text: "oṁ"}}}
+	if cues := captionCues(untimed, "line"); len(cues) != 0 {
+		t.Errorf("cues of untimed words: %+v", cues)
+	}
+}
+
+func TestWriteWebVTT(t *testing.T) {
+	want := "WEBVTT\n" +
+		"\n1\n00:00:00.000 --> 00:00:02.500\ndharma-kṣetre <00:00:01.200>kuru-kṣetre\n" +
+		"\n2\n00:00:02.500 --> 00:00:05.000\nsamavetā <00:00:03.750>&lt;yuyutsavaḥ&gt;\n"
+	if got := string(writeWebVTT(captionCues(captionWords, "line"))); got != want {
+		t.Errorf("writeWebVTT =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteSRT(t *testing.T) {
+	want := "1\n00:00:00,000 --> 00:00:01,200\ndharma-kṣetre\n\n" +
+		"2\n00:00:01,200 --> 00:00:02,500\nkuru-kṣetre\n\n" +
+		"3\n00:00:02,500 --> 00:00:03,750\nsamavetā\n\n" +
+		"4\n00:00:03,750 --> 00:00:05,000\n<yuyutsavaḥ>\n\n"
+	if got := string(writeSRT(captionCues(captionWords, "word"))); got != want {
+		t.Errorf("writeSRT =\n%s\nwant\n%s", got, want)
+	}
+}
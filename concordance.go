@@ -0,0 +1,392 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// iastAlphabet - IAST letters in Sanskrit alphabetical order; aspirates and diphthongs are single letters
+var iastAlphabet = []string{
+	"a", "ā", "i", "ī", "u", "ū", "ṛ", "ṝ", "ḷ", "ḹ", "e", "ai", "o", "au", "ṁ", "ḥ",
+	"k", "kh", "g", "gh", "ṅ", "c", "ch", "j", "jh", "ñ",
+	"ṭ", "ṭh", "ḍ", "ḍh", "ṇ", "t", "th", "d", "dh", "n",
+	"p", "ph", "b", "bh", "m", "y", "r", "l", "v", "ś", "ṣ", "s", "h",
+}
+
+// iastRanks - position of every IAST letter in iastAlphabet, counting from 1
+var iastRanks = map[string]int{}
+
+func init() {
+	for i, letter := range iastAlphabet {
+		iastRanks[letter] = i + 1
+	}
+}
+
+// iastLetters - splits an IAST word into letters, preferring two-character letters: "bhakti" -> bh a k t i
+func iastLetters(word string) []string {
+	runes := []rune(word)
+	var letters []string
+	for i := 0; i < len(runes); i++ {
+		if i+1 < len(runes) {
+			if _, ok := iastRanks[string(runes[i:i+2])]; ok {
+				letters = append(letters, string(runes[i:i+2]))
+				i++
+				continue
+			}
+		}
+		letters = append(letters, string(runes[i]))
+	}
+	return letters
+}
+
+// iastLess - IAST alphabetical order: a < ā < i ... < k < kh ... < h. Hyphens and spaces sort before letters.
+func iastLess(a, b string) bool {
+	lettersA, lettersB := iastLetters(a), iastLetters(b)
+	for i := 0; i < len(lettersA) && i < len(lettersB); i++ {
+		rankA, rankB := iastRanks[lettersA[i]], iastRanks[lettersB[i]]
+		if rankA != rankB {
+			return rankA < rankB
+		}
+		if rankA == 0 && lettersA[i] != lettersB[i] {
+			return lettersA[i] < lettersB[i]
+		}
+	}
+	return len(lettersA) < len(lettersB)
+}
+
+// normaliseTerm - form of a synonym: lowercase, trimmed, single spaces, ṁ for anusvāra
+func normaliseTerm(synonym string) string {
+	term := strings.Join(strings.Fields(strings.ToLower(synonym)), " ")
+	return strings.NewReplacer("ḿ", "ṁ", "ṃ", "ṁ").Replace(term)
+}
+
+// iastEndings - case endings of the a-stems, the commonest declension, longest first
+var iastEndings = []string{"ebhyaḥ", "ānām", "āṇām", "asya", "aiḥ", "eṣu", "āni", "āṇi", "ena", "eṇa", "āya", "āt", "ān", "āḥ", "aḥ", "am", "e"}
+
+// termStem - a-stem shared by the inflected forms of a single word: dharmaḥ, dharmam, dharmasya, dharme -> dharma.
+// Without a dictionary only the a-stem endings are stripped and only if three letters remain, so words of other
+// declensions may keep some forms apart and, rarely, join an a-stem; compounds and phrases are kept whole.
+func termStem(term string) string {
+	if termParts(term) != nil {
+		return term
+	}
+	for _, ending := range iastEndings {
+		if stem := strings.TrimSuffix(term, ending); stem != term && len(iastLetters(stem)) >= 3 {
+			return stem + "a"
+		}
+	}
+	return term
+}
+
+// termParts - members of a compound or a phrase: "dharma-kṣetre" -> dharma, kṣetre; nil for a single word
+func termParts(term string) []string {
+	parts := strings.FieldsFunc(term, func(r rune) bool { return r == '-' || r == ' ' })
+	if len(parts) < 2 {
+		return nil
+	}
+	return parts
+}
+
+// Occurrence - a Sanskrit word in the synonyms of a verse
+type Occurrence struct {
+	Chapter     int
+	Verse       int
+	Form        string        // synonym as given in the verse, e.g. "dharma-kṣetre" for the term "dharma"
+	Translation template.HTML // meaning of the synonym in this verse
+}
+
+// ConcordanceEntry - Sanskrit term with all its occurrences in reading order
+type ConcordanceEntry struct {
+	Term        string
+	Occurrences []Occurrence
+}
+
+// concordance - index of the Sanskrit words of the word-for-word synonyms
+type concordance struct {
+	entries map[string]*ConcordanceEntry // by headword
+	terms   []string                     // headwords in IAST order
+	forms   map[string]string            // inflected form -> headword, for the forms which are not headwords
+	folded  map[string]string            // foldWord(headword or form) -> headword, for URLs typed without diacritics
+}
+
+// newConcordance - indexes the synonyms of every verse under the whole synonym and each member of a compound;
+// the forms of a single word with the same termStem are merged under one headword: the stem if it occurs
+// as a form of its own, else the form with the most occurrences
+func newConcordance(book *Book) *concordance {
+	c := &concordance{entries: map[string]*ConcordanceEntry{}, forms: map[string]string{}, folded: map[string]string{}}
+	byForm := map[string][]Occurrence{}
+	add := func(term string, occurrence Occurrence) {
+		occurrences := byForm[term]
+		if n := len(occurrences); n > 0 && occurrences[n-1] == occurrence {
+			return // the same member twice in one synonym
+		}
+		byForm[term] = append(occurrences, occurrence)
+	}
+
+	for _, chapter := range book.Chapters {
+		for _, verse := range chapter.Verses {
+			for i, synonym := range verse.SynonymsSanskrit {
+				term := normaliseTerm(synonym)
+				if term == "" || i >= len(verse.SynonymsTranslation) {
+					continue
+				}
+				occurrence := Occurrence{Chapter: chapter.Num, Verse: verse.Num, Form: term, Translation: verse.SynonymsTranslation[i]}
+				add(term, occurrence)
+				for _, part := range termParts(term) {
+					add(part, occurrence)
+				}
+			}
+		}
+	}
+
+	// forms by their stem, in IAST order for a stable choice of the headword
+	var forms []string
+	for form := range byForm {
+		forms = append(forms, form)
+	}
+	sort.Slice(forms, func(i, j int) bool { return iastLess(forms[i], forms[j]) })
+	stems := map[string][]string{}
+	for _, form := range forms {
+		stems[termStem(form)] = append(stems[termStem(form)], form)
+	}
+
+	for stem, stemForms := range stems {
+		headword := stemForms[0]
+		for _, form := range stemForms {
+			if form == stem {
+				headword = form
+				break
+			}
+			if len(byForm[form]) > len(byForm[headword]) {
+				headword = form
+			}
+		}
+		entry := &ConcordanceEntry{Term: headword}
+		for _, form := range stemForms {
+			entry.Occurrences = append(entry.Occurrences, byForm[form]...)
+			if form != headword {
+				c.forms[form] = headword
+			}
+		}
+		// the forms in reading order, a synonym with the forms of two members of a compound only once
+		sort.SliceStable(entry.Occurrences, func(i, j int) bool {
+			a, b := entry.Occurrences[i], entry.Occurrences[j]
+			return a.Chapter < b.Chapter || (a.Chapter == b.Chapter && a.Verse < b.Verse)
+		})
+		occurrences := entry.Occurrences[:0]
+		for _, occurrence := range entry.Occurrences {
+			if n := len(occurrences); n == 0 || occurrences[n-1] != occurrence {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+		entry.Occurrences = occurrences
+		c.entries[headword] = entry
+		c.terms = append(c.terms, headword)
+	}
+	sort.Slice(c.terms, func(i, j int) bool { return iastLess(c.terms[i], c.terms[j]) })
+
+	// the headwords take precedence over the forms, the first one in IAST order over the later ones
+	for i := len(forms) - 1; i >= 0; i-- {
+		if headword, ok := c.forms[forms[i]]; ok {
+			c.folded[foldWord(forms[i])] = headword
+		}
+	}
+	for i := len(c.terms) - 1; i >= 0; i-- {
+		c.folded[foldWord(c.terms[i])] = c.terms[i]
+	}
+	return c
+}
+
+// headword - headword of the concordance entry of a form, "" if the form is in no synonym
+func (c *concordance) headword(form string) string {
+	if _, ok := c.entries[form]; ok {
+		return form
+	}
+	return c.forms[form]
+}
+
+// termURL - URL of the concordance page of a term: /lt/zodynas/dharma
+func termURL(languageID, term string) (*url.URL, error) {
+	return router.Get("langGlossaryTerm").URL("language", languageID, "glossary", pageSlug(languageID, "glossary"), "term", term)
+}
+
+// termURLs - URL builder of the concordance page of a term in every language whose synonyms have the term
+func termURLs(term string) func(languageID string) (*url.URL, error) {
+	return func(languageID string) (*url.URL, error) {
+		if _, ok := Corpora()[languageID].concordance.entries[term]; !ok {
+			return nil, nil
+		}
+		return router.Get("langGlossaryTerm").URL("language", languageID, "glossary", pageSlug(languageID, "glossary"), "term", term)
+	}
+}
+
+// LangGlossaryHandler - alphabetical index of the Sanskrit terms: /lt/zodynas
+func LangGlossaryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+
+	fp := path.Join("templates", "glossary.html")
+	tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	// Group terms by their first IAST letter
+	type letterGroup struct {
+		Letter string
+		Terms  []template.HTML
+	}
+	var groups []letterGroup
+	for _, term := range book.concordance.terms {
+		letter := iastLetters(term)[0]
+		if len(groups) == 0 || groups[len(groups)-1].Letter != letter {
+			groups = append(groups, letterGroup{Letter: letter})
+		}
+		termURL, err := termURL(vars["language"], term)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		group := &groups[len(groups)-1]
+		group.Terms = append(group.Terms, template.HTML(fmt.Sprintf(`<a href="%s">%s</a> (%d)`,
+			termURL.String(), template.HTMLEscapeString(term), len(book.concordance.entries[term].Occurrences))))
+	}
+
+	upURL, urlErr := router.Get("langIndex").URL("language", vars["language"])
+	if urlErr != nil {
+		serverError(w, r, urlErr)
+		return
+	}
+
+	meta, err := newPageMeta(r, vars["language"], pageName(vars["language"], "glossary")+" – "+localised(bookTitles, vars["language"]),
+		localised(bookDescriptions, vars["language"]),
+		func(languageID string) (*url.URL, error) {
+			return router.Get("langGlossary").URL("language", languageID, "glossary", pageSlug(languageID, "glossary"))
+		})
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"languageId": vars["language"],
+		"meta":       meta,
+		"groups":     groups,
+		"up":         template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String())),
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		serverError(w, r, err)
+	}
+}
+
+// LangGlossaryTermHandler - every verse where a Sanskrit term appears: /lt/zodynas/dharma
+func LangGlossaryTermHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+	term := normaliseTerm(vars["term"])
+
+	entry, ok := book.concordance.entries[term]
+	if !ok {
+		// Inflected forms and terms typed without diacritics: /lt/zodynas/dharmasya -> /lt/zodynas/dharma,
+		// /lt/zodynas/krisna -> /lt/zodynas/kṛṣṇa
+		canonical, ok := book.concordance.forms[term]
+		if !ok {
+			canonical, ok = book.concordance.folded[foldWord(term)]
+		}
+		if ok {
+			canonicalURL, err := termURL(vars["language"], canonical)
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, canonicalURL.String(), 301)
+			return
+		}
+		searchURL, err := router.Get("langSearch").URL("language", vars["language"], "search", pageSlug(vars["language"], "search"))
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		searchURL.RawQuery = url.Values{"q": {vars["term"]}}.Encode()
+		glossaryURL, err := router.Get("langGlossary").URL("language", vars["language"], "glossary", pageSlug(vars["language"], "glossary"))
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "term"), vars["term"]), []Suggestion{
+			{URL: searchURL.String(), Title: pageName(vars["language"], "search") + ": " + vars["term"]},
+			{URL: glossaryURL.String(), Title: pageName(vars["language"], "glossary")},
+		})
+		return
+	}
+
+	fp := path.Join("templates", "term.html")
+	tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	// construct occurrences list
+	var occurrencesList []template.HTML
+	for _, occurrence := range entry.Occurrences {
+		verseURL, err := verseURL(vars["language"], book, occurrence.Chapter, occurrence.Verse)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		verseNumHref := template.HTML(fmt.Sprintf(`<a href="%s">%v.%v</a>`, verseURL.String(), occurrence.Chapter, occurrence.Verse))
+		form := template.HTML(template.HTMLEscapeString(occurrence.Form))
+		if headword := book.concordance.headword(occurrence.Form); headword != entry.Term {
+			formURL, err := termURL(vars["language"], headword)
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			form = template.HTML(fmt.Sprintf(`<a href="%s">%s</a>`, formURL.String(), form))
+		}
+		occurrencesList = append(occurrencesList, "<td valign=\"top\">"+verseNumHref+"</td><td><i>"+form+"</i></td><td>"+occurrence.Translation+"</td>")
+	}
+
+	upURL, urlErr := router.Get("langGlossary").URL("language", vars["language"], "glossary", pageSlug(vars["language"], "glossary"))
+	if urlErr != nil {
+		serverError(w, r, urlErr)
+		return
+	}
+
+	// translations of the first occurrences
+	var translations []string
+	for _, occurrence := range entry.Occurrences {
+		if len(translations) == 5 {
+			break
+		}
+		translations = append(translations, plainText(occurrence.Translation))
+	}
+	meta, err := newPageMeta(r, vars["language"], entry.Term+" – "+pageName(vars["language"], "glossary"),
+		excerpt(entry.Term+": "+strings.Join(translations, "; "), 160), termURLs(entry.Term))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"languageId":  vars["language"],
+		"meta":        meta,
+		"term":        entry.Term,
+		"occurrences": occurrencesList,
+		"up":          template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String())),
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		serverError(w, r, err)
+	}
+}
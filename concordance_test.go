@@ -0,0 +1,74 @@
+package main
+
+import (
+	"html/template"
+	"reflect"
+	"testing"
+)
+
+func TestTermStem(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"dharma", "dharma"},
+		{"dharmaḥ", "dharma"},
+		{"dharmam", "dharma"},
+		{"dharmasya", "dharma"},
+		{"dharme", "dharma"},
+		{"kṣetre", "kṣetra"},
+		{"karmāṇi", "karma"},
+		{"putrāṇām", "putra"},
+		{"devebhyaḥ", "deva"},
+		{"kṛṣṇa", "kṛṣṇa"},
+		{"gītā", "gītā"},
+		// too short to strip, or not an a-stem ending
+		{"aham", "aham"},
+		{"te", "te"},
+		{"muniḥ", "muniḥ"},
+		// compounds and phrases are kept whole
+		{"dharma-kṣetre", "dharma-kṣetre"},
+		{"na ca", "na ca"},
+	}
+	for _, test := range tests {
+		if got := termStem(test.term); got != test.want {
+			t.Errorf("termStem(%q) = %q, want %q", test.term, got, test.want)
+		}
+	}
+}
+
+func TestConcordanceForms(t *testing.T) {
+	book := &Book{}
+	book.Chapters[0] = Chapter{Num: 1, Verses: []Verse{
+		{Num: 1, SynonymsSanskrit: []string{"dharma-kṣetre", "dharmasya"}, SynonymsTranslation: []template.HTML{"in the place of pilgrimage", "of religion"}},
+		{Num: 2, SynonymsSanskrit: []string{"dharmam"}, SynonymsTranslation: []template.HTML{"religion"}},
+		{Num: 3, SynonymsSanskrit: []string{"dharma"}, SynonymsTranslation: []template.HTML{"religion"}},
+	}}
+	c := newConcordance(book)
+
+	entry, ok := c.entries["dharma"]
+	if !ok {
+		t.Fatalf("no headword dharma among %v", c.terms)
+	}
+	var forms []string
+	for _, occurrence := range entry.Occurrences {
+		forms = append(forms, occurrence.Form)
+	}
+	if want := []string{"dharma-kṣetre", "dharmasya", "dharmam", "dharma"}; !reflect.DeepEqual(forms, want) {
+		t.Errorf("occurrences of dharma %v, want %v", forms, want)
+	}
+	for _, form := range []string{"dharmasya", "dharmam"} {
+		if _, ok := c.entries[form]; ok {
+			t.Errorf("inflected form %s is a headword", form)
+		}
+		if got := c.headword(form); got != "dharma" {
+			t.Errorf("headword(%q) = %q, want dharma", form, got)
+		}
+	}
+	if got := c.headword("dharma-kṣetre"); got != "dharma-kṣetre" {
+		t.Errorf("headword of the compound %q", got)
+	}
+	if got := c.folded["dharmasya"]; got != "dharma" {
+		t.Errorf("folded dharmasya -> %q, want dharma", got)
+	}
+}
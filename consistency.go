@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// diffOp - run of a line diff: '=' in both, '-' only in the transliterated Devanagari, '+' only in the IAST
+type diffOp struct {
+	Kind byte   `json:"kind"`
+	Text string `json:"text"`
+}
+
+// LineMismatch - a Devanagari line disagreeing with its IAST line
+type LineMismatch struct {
+	File       string   `json:"file,omitempty"`
+	Location   string   `json:"location"` // chapter.verse:line, e.g. "1.1:1"
+	Devanagari string   `json:"devanagari"`
+	Expected   string   `json:"expected"` // the Devanagari transliterated to IAST
+	IAST       string   `json:"iast"`
+	Diff       []diffOp `json:"diff,omitempty"`
+	Issues     []string `json:"issues,omitempty"`
+}
+
+// comparableTokens - letters and avagrahas of a line, with the nasal before a consonant read as anusvāra,
+// so that संजय and sañjaya compare equal. Word boundaries are returned as positions in the letters.
+func comparableTokens(tokens []translit.Token) (letters []translit.Token, boundaries map[int]bool) {
+	boundaries = map[int]bool{}
+	for _, token := range tokens {
+		if token.IsLetter() || token.Text == "'" {
+			letters = append(letters, token)
+		} else if (token.Text == " " || token.Text == "-") && len(letters) > 0 {
+			boundaries[len(letters)] = true
+		}
+	}
+	delete(boundaries, len(letters)) // space before a danda
+	for i := 0; i+1 < len(letters); i++ {
+		switch letters[i].Text {
+		case "ṅ", "ñ", "ṇ", "n", "m":
+			if letters[i+1].Kind == translit.Consonant {
+				letters[i] = translit.Token{Kind: translit.Modifier, Text: "ṁ"}
+			}
+		}
+	}
+	return letters, boundaries
+}
+
+// diffTokens - shortest edit script between two token sequences as runs of IAST text
+func diffTokens(a, b []translit.Token) []diffOp {
+	// lcs[i][j] - length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	add := func(kind byte, token translit.Token) {
+		text := translit.Render([]translit.Token{token}, translit.IAST)
+		if n := len(ops); n > 0 && ops[n-1].Kind == kind {
+			ops[n-1].Text += text
+		} else {
+			ops = append(ops, diffOp{Kind: kind, Text: text})
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			add('=', a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			add('-', a[i])
+			i++
+		default:
+			add('+', b[j])
+			j++
+		}
+	}
+	return ops
+}
+
+// checkLine - compares a Devanagari line with its IAST line; nil if they agree
+func checkLine(devanagari, iast string) *LineMismatch {
+	devaTokens := translit.ParseDevanagari(devanagari)
+	mismatch := &LineMismatch{
+		Devanagari: devanagari,
+		Expected:   strings.TrimSpace(translit.Render(devaTokens, translit.IAST)),
+		IAST:       iast,
+	}
+
+	if strings.Contains(iast, "  ") {
+		mismatch.Issues = append(mismatch.Issues, "double space")
+	}
+	if strings.TrimSpace(iast) != iast {
+		mismatch.Issues = append(mismatch.Issues, "leading or trailing space")
+	}
+
+	devaLetters, devaBoundaries := comparableTokens(devaTokens)
+	iastLetters, iastBoundaries := comparableTokens(translit.ParseIAST(iast))
+	diff := diffTokens(devaLetters, iastLetters)
+	if len(diff) > 1 || (len(diff) == 1 && diff[0].Kind != '=') {
+		mismatch.Diff = diff
+	} else {
+		// Same letters: every word boundary of the Devanagari has to be one in the IAST too
+		positions := []int{0}
+		for position := range devaBoundaries {
+			positions = append(positions, position)
+		}
+		sort.Ints(positions)
+		positions = append(positions, len(devaLetters))
+		for i := 1; i < len(positions)-1; i++ {
+			if !iastBoundaries[positions[i]] {
+				mismatch.Issues = append(mismatch.Issues, fmt.Sprintf("no word boundary in IAST between %q and %q",
+					translit.Render(devaLetters[positions[i-1]:positions[i]], translit.IAST),
+					translit.Render(devaLetters[positions[i]:positions[i+1]], translit.IAST)))
+			}
+		}
+	}
+
+	if mismatch.Diff == nil && mismatch.Issues == nil {
+		return nil
+	}
+	return mismatch
+}
+
+// checkIAST - Devanagari and IAST lines of every verse which disagree
+func checkIAST(book *Book) []LineMismatch {
+	var mismatches []LineMismatch
+	for _, chapter := range book.Chapters {
+		for _, verse := range chapter.Verses {
+			lines := len(verse.Devanagari)
+			if len(verse.IAST) > lines {
+				lines = len(verse.IAST)
+			}
+			for lineIdx := 0; lineIdx < lines; lineIdx++ {
+				var devanagari, iast string
+				if lineIdx < len(verse.Devanagari) {
+					devanagari = verse.Devanagari[lineIdx]
+				}
+				if lineIdx < len(verse.IAST) {
+					iast = verse.IAST[lineIdx]
+				}
+				if mismatch := checkLine(devanagari, iast); mismatch != nil {
+					mismatch.Location = fmt.Sprintf("%d.%d:%d", chapter.Num, verse.Num, lineIdx+1)
+					mismatches = append(mismatches, *mismatch)
+				}
+			}
+		}
+	}
+	return mismatches
+}
+
+// DiffText - the diff in word-diff notation: common[-only in Devanagari-]{+only in IAST+}
+func (m LineMismatch) DiffText() string {
+	var out bytes.Buffer
+	for _, op := range m.Diff {
+		switch op.Kind {
+		case '-':
+			out.WriteString("[-" + op.Text + "-]")
+		case '+':
+			out.WriteString("{+" + op.Text + "+}")
+		default:
+			out.WriteString(op.Text)
+		}
+	}
+	return out.String()
+}
+
+// DiffHTML - the diff with <del> for letters only in the Devanagari and <ins> for letters only in the IAST
+func (m LineMismatch) DiffHTML() template.HTML {
+	var out bytes.Buffer
+	for _, op := range m.Diff {
+		text := template.HTMLEscapeString(op.Text)
+		switch op.Kind {
+		case '-':
+			out.WriteString("<del>" + text + "</del>")
+		case '+':
+			out.WriteString("<ins>" + text + "</ins>")
+		default:
+			out.WriteString(text)
+		}
+	}
+	return template.HTML(out.String())
+}
+
+// checkIASTCommand - "check-iast [-json] [file.json...]" subcommand: reports Devanagari and IAST lines which disagree.
+// Exit code is 0 if all lines agree, 1 if there are mismatches, 2 if a file cannot be loaded.
+func checkIASTCommand(args []string) int {
+	flags := flag.NewFlagSet("check-iast", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print mismatches as JSON lines")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check-iast [-json] [file.json...]\nWithout files checks all corpora in %s\n", os.Args[0], textsDir)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	files := flags.Args()
+	if len(files) == 0 {
+		corpusFiles, err := discoverCorpora(textsDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, file := range corpusFiles {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+	}
+
+	exitCode := 0
+	out := json.NewEncoder(os.Stdout)
+	out.SetEscapeHTML(false)
+	for _, file := range files {
+		book, err := loadBook(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+			continue
+		}
+		for _, mismatch := range checkIAST(book) {
+			if exitCode == 0 {
+				exitCode = 1
+			}
+			mismatch.File = file
+			if *jsonOutput {
+				out.Encode(mismatch)
+				continue
+			}
+			fmt.Printf("%s:%s\n", file, mismatch.Location)
+			fmt.Printf("  devanagari: %s\n  iast:       %s\n", mismatch.Devanagari, mismatch.IAST)
+			if mismatch.Diff != nil {
+				fmt.Printf("  diff:       %s\n", mismatch.DiffText())
+			}
+			for _, issue := range mismatch.Issues {
+				fmt.Printf("  issue:      %s\n", issue)
+			}
+		}
+	}
+	return exitCode
+}
@@ -7,13 +7,52 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// textsDir - root directory of the corpora, one subdirectory per language: public/texts/{lang}/*.json
+var textsDir = "./public/texts"
+
+// Content - loaded books by language ID and the recitation catalogue timed against them
+type Content struct {
+	Corpora     map[string]*Book
+	Recitations *Catalogue
+}
+
+// content - the loaded *Content, replaced as a whole on reload, so a request never sees a half-built Book
+// nor the corpora of one load with the catalogue of another
+var content atomic.Value
+
+// Corpora - currently loaded books by language ID. The map must not be modified.
+func Corpora() map[string]*Book {
+	return content.Load().(*Content).Corpora
+}
+
 // Book - top level structure of a book
 type Book struct {
-	Preface      string
-	Introduction string
+	Preface      []template.HTML
+	Introduction []template.HTML
 	Chapters     [18]Chapter
+
+	search      *searchIndex // full-text index of translations, synonyms and purports
+	concordance *concordance // Sanskrit words of the synonyms
+	modTime     time.Time    // of the corpus file, the last change of the content
+}
+
+// Section - paragraphs of a front matter section by its key: "preface" or "introduction"
+func (b *Book) Section(key string) []template.HTML {
+	switch key {
+	case "preface":
+		return b.Preface
+	case "introduction":
+		return b.Introduction
+	}
+	return nil
 }
 
 // Chapter - individual chapter in a book
@@ -27,67 +66,225 @@ type Chapter struct {
 
 // Verse - individual verse in a chapter
 type Verse struct {
-	Num                   int
-	Devanagari            []string
-	DevanagariWordTimings []float32
-	IAST                  []string
-	IASTWordTimings       []float32
-	SynonymsSanskrit      []string
-	SynonymsTranslation   []template.HTML
-	Translation           template.HTML
-	Purport               []template.HTML
-	PrevVerse             [2]int // [ChapterNum, VerseNum] - Num, not Idx!
-	NextVerse             [2]int
+	Num                 int
+	Devanagari          []string
+	IAST                []string
+	SynonymsSanskrit    []string
+	SynonymsTranslation []template.HTML
+	Translation         template.HTML
+	Purport             []template.HTML
+	PrevVerse           [2]int // [ChapterNum, VerseNum] - Num, not Idx!
+	NextVerse           [2]int
+	From                int      // joined verse group From..To this verse belongs to; From == To == Num for a single verse
+	To                  int      // the last verse of a group carries its Translation and Purport
+	DevanagariWords     [][]Word `json:"-"` // words of the Devanagari lines, timed by recitations
+	IASTWords           [][]Word `json:"-"`
+}
+
+// Ref - verse reference as used in URLs: "13" or "16-18" for a joined verse group
+func (v Verse) Ref() string {
+	if v.From == v.To {
+		return strconv.Itoa(v.From)
+	}
+	return fmt.Sprintf("%d-%d", v.From, v.To)
+}
+
+// Group - all verses of the group verse number verseNum belongs to
+func (c Chapter) Group(verseNum int) []Verse {
+	verse := c.Verses[verseNum-1]
+	return c.Verses[verse.From-1 : verse.To]
+}
+
+// parseVerseRef - parses verse route value "13" or "16-18" into the first and the last verse number
+func parseVerseRef(ref string) (from, to int) {
+	parts := strings.SplitN(ref, "-", 2)
+	from, _ = strconv.Atoi(parts[0])
+	to = from
+	if len(parts) == 2 {
+		to, _ = strconv.Atoi(parts[1])
+	}
+	return from, to
+}
+
+// languages - sorted IDs of the loaded corpora
+func languages() []string {
+	var langs []string
+	for lang := range Corpora() {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// discoverCorpora - finds corpus files in textsDir, one per language.
+// If a language directory holds several files, the last one in lexical order (the newest edition) wins.
+func discoverCorpora(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	corpusFiles := map[string]string{}
+	for _, file := range files {
+		lang := filepath.Base(filepath.Dir(file))
+		if prev, ok := corpusFiles[lang]; ok {
+			log.Printf("[%s] %s supersedes %s", lang, file, prev)
+		}
+		corpusFiles[lang] = file
+	}
+	return corpusFiles, nil
 }
 
 func loadJSON() {
-	data, err := ioutil.ReadFile("./public/texts/lt/83.json")
+	corpusFiles, err := discoverCorpora(textsDir)
 	if err != nil {
 		fmt.Printf("%v", err)
 		os.Exit(2)
 	}
-	if err := json.Unmarshal(data, &BG); err != nil {
-		log.Fatalf("JSON unmarshalling failed: %s", err)
+	if len(corpusFiles) == 0 {
+		log.Fatalf("No corpora found in %s", textsDir)
+	}
+
+	books := map[string]*Book{}
+	for lang, file := range corpusFiles {
+		book, err := loadCorpus(lang, file)
+		if err != nil {
+			log.Fatalf("[%s] %s", lang, err)
+		}
+		books[lang] = book
+	}
+
+	if _, ok := books[defaultLangID]; !ok {
+		log.Fatalf("No corpus for the default language %q in %s", defaultLangID, textsDir)
+	}
+
+	catalogue, err := loadRecitations(books[defaultLangID])
+	if err != nil {
+		log.Fatal(err)
+	}
+	content.Store(&Content{Corpora: books, Recitations: catalogue})
+}
+
+// loadRecitations - loads the recitation catalogue and validates its timings against the book; warnings are logged
+func loadRecitations(book *Book) (*Catalogue, error) {
+	catalogue, err := loadCatalogue(catalogueFile())
+	if err != nil {
+		return nil, err
+	}
+	for _, violation := range validateCatalogue(catalogue, book) {
+		log.Printf("[%s] %s %s: %s: %s", catalogueName, violation.Severity, violation.Location, violation.Rule, violation.Message)
+	}
+	log.Printf("loaded %s", catalogueFile())
+	return catalogue, nil
+}
+
+// loadCorpus - loads and validates a corpus file. Validation warnings are logged, errors fail the load.
+func loadCorpus(lang, file string) (*Book, error) {
+	book, err := loadBook(file)
+	if err != nil {
+		return nil, err
+	}
+
+	errors := 0
+	for _, violation := range validateBook(book) {
+		log.Printf("[%s] %s %s: %s: %s", lang, violation.Severity, violation.Location, violation.Rule, violation.Message)
+		if violation.Severity == "error" {
+			errors++
+		}
+	}
+	if errors > 0 {
+		return nil, fmt.Errorf("%s: %d validation errors", file, errors)
+	}
+
+	book.search = newSearchIndex(book, lang)
+	book.concordance = newConcordance(book)
+
+	log.Printf("[%s] loaded %s", lang, file)
+	return book, nil
+}
+
+// loadBook - reads a single corpus file and sets up the navigation between chapters and verses
+func loadBook(filename string) (*Book, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var book Book
+	if err := json.Unmarshal(data, &book); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of %s failed: %s", filename, err)
+	}
+	if info, err := os.Stat(filename); err == nil {
+		book.modTime = info.ModTime()
+	}
+
+	for chapterIdx, chapter := range book.Chapters {
+		if len(chapter.Verses) == 0 {
+			return nil, fmt.Errorf("%s: chapter %d has no verses", filename, chapterIdx+1)
+		}
+	}
+
+	// Split the lines into words for the highlighting during the recitation
+	for chapterIdx := range book.Chapters {
+		for verseIdx := range book.Chapters[chapterIdx].Verses {
+			verse := &book.Chapters[chapterIdx].Verses[verseIdx]
+			verse.DevanagariWords = verseLineWords(verse.Devanagari)
+			verse.IASTWords = verseLineWords(verse.IAST)
+		}
+	}
+
+	// Join verse groups: a verse with an empty translation shares the translation and purport of the following one, e.g. 1.16-18
+	for chapterIdx := range book.Chapters {
+		verses := book.Chapters[chapterIdx].Verses
+		for from := 0; from < len(verses); {
+			to := from
+			for to < len(verses)-1 && verses[to].Translation == "" {
+				to++
+			}
+			for verseIdx := from; verseIdx <= to; verseIdx++ {
+				verses[verseIdx].From = from + 1
+				verses[verseIdx].To = to + 1
+			}
+			from = to + 1
+		}
 	}
 
 	// Set Prev and Next link values:
-	for chapterIdx := range BG.Chapters {
-		BG.Chapters[chapterIdx].PrevChapter = BG.Chapters[chapterIdx].Num - 1
-		BG.Chapters[chapterIdx].NextChapter = BG.Chapters[chapterIdx].Num + 1
+	for chapterIdx := range book.Chapters {
+		book.Chapters[chapterIdx].PrevChapter = book.Chapters[chapterIdx].Num - 1
+		book.Chapters[chapterIdx].NextChapter = book.Chapters[chapterIdx].Num + 1
 		if chapterIdx == 0 {
-			BG.Chapters[chapterIdx].PrevChapter = 0
-		} else if chapterIdx == len(BG.Chapters)-1 {
-			BG.Chapters[chapterIdx].NextChapter = 0
+			book.Chapters[chapterIdx].PrevChapter = 0
+		} else if chapterIdx == len(book.Chapters)-1 {
+			book.Chapters[chapterIdx].NextChapter = 0
 		}
-		for verseIdx, verse := range BG.Chapters[chapterIdx].Verses {
 
-			// Default values for most of verses
-			verse.PrevVerse[0] = chapterIdx + 1
-			verse.PrevVerse[1] = verseIdx + 1 - 1
-			verse.NextVerse[0] = chapterIdx + 1
-			verse.NextVerse[1] = verseIdx + 1 + 1
+		// Prev and Next step over whole verse groups and point to the first verse of a group
+		verses := book.Chapters[chapterIdx].Verses
+		for verseIdx := range verses {
+			verse := &verses[verseIdx]
 
-			// Exceptions
-			if chapterIdx == 0 && verseIdx == 0 {
-				// Very first verse - no Prev
-				verse.PrevVerse[0] = 0
-				verse.PrevVerse[1] = 0
-			} else if chapterIdx == len(BG.Chapters)-1 && verseIdx == len(BG.Chapters[chapterIdx].Verses)-1 {
-				// Very last verse - no Next
-				verse.NextVerse[0] = 0
-				verse.NextVerse[1] = 0
-			} else if verseIdx == 0 {
+			if verse.From > 1 {
+				verse.PrevVerse = [2]int{chapterIdx + 1, verses[verse.From-2].From}
+			} else if chapterIdx > 0 {
 				// First verse of a chapter
-				verse.PrevVerse[0]--
-				verse.PrevVerse[1] = len(BG.Chapters[chapterIdx-1].Verses)
-			} else if verseIdx == len(BG.Chapters[chapterIdx].Verses)-1 {
-				// Last verse of a chapter
-				verse.NextVerse[0]++
-				verse.NextVerse[1] = 1
+				prevVerses := book.Chapters[chapterIdx-1].Verses
+				verse.PrevVerse = [2]int{chapterIdx, prevVerses[len(prevVerses)-1].From}
+			} else {
+				// Very first verse - no Prev
+				verse.PrevVerse = [2]int{0, 0}
 			}
 
-			BG.Chapters[chapterIdx].Verses[verseIdx].PrevVerse = verse.PrevVerse
-			BG.Chapters[chapterIdx].Verses[verseIdx].NextVerse = verse.NextVerse
+			if verse.To < len(verses) {
+				verse.NextVerse = [2]int{chapterIdx + 1, verse.To + 1}
+			} else if chapterIdx < len(book.Chapters)-1 {
+				// Last verse of a chapter
+				verse.NextVerse = [2]int{chapterIdx + 2, 1}
+			} else {
+				// Very last verse - no Next
+				verse.NextVerse = [2]int{0, 0}
+			}
 		}
 	}
+	return &book, nil
 }
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// errorTexts - localised texts of the error pages by language
+var errorTexts = map[string]map[string]string{
+	"lt": {
+		"notFound":     "Puslapis nerastas",
+		"chapter":      "Skyriaus %v nėra",
+		"verse":        "Posmo %v.%v nėra",
+		"section":      "Skyriaus „%v“ nėra",
+		"term":         "Žodžio „%v“ žodyne nėra",
+		"page":         "Puslapio %v nėra",
+		"suggestions":  "Galbūt ieškojote:",
+		"serverError":  "Serverio klaida",
+		"serverDetail": "Atsiprašome, puslapio parodyti nepavyko. Pabandykite vėliau.",
+		"contents":     "Turinys",
+		"badReference": "Netinkama nuoroda",
+		"reference":    "Nuorodos „%v“ nepavyko suprasti. Rašykite, pvz., BG 2.13 arba 2 skyrius 13 posmas.",
+		"badRequest":   "Netinkama užklausa",
+		"unauthorized": "Reikia prisijungti",
+		"login":        "Šis puslapis skirtas redaktoriams, prisijunkite kaip admin.",
+		"language":     "Kalbos „%v“ nėra",
+		"recitation":   "Įrašo „%v“ nėra",
+		"track":        "Takelio „%v“ nėra, galimi iast arba devanagari",
+		"cues":         "Titrų „%v“ nėra, galimi line arba word",
+		"timings":      "Posmo %v.%v įraše %v nėra %v žodžių laikų",
+	},
+	"en": {
+		"notFound":     "Page not found",
+		"chapter":      "Chapter %v does not exist",
+		"verse":        "Verse %v.%v does not exist",
+		"section":      "Section %v does not exist",
+		"term":         "Term %v is not in the glossary",
+		"page":         "Page %v does not exist",
+		"suggestions":  "Perhaps you were looking for:",
+		"serverError":  "Server error",
+		"serverDetail": "Sorry, the page could not be shown. Please try again later.",
+		"contents":     "Contents",
+		"badReference": "Invalid reference",
+		"reference":    "Could not understand the reference %q. Write e.g. BG 2.13 or chapter 2 verse 13.",
+		"badRequest":   "Bad request",
+		"unauthorized": "Login required",
+		"login":        "This page is for the editors, log in as admin.",
+		"language":     "Unknown language %q",
+		"recitation":   "Unknown recitation %q",
+		"track":        "Unknown track %q, expected iast or devanagari",
+		"cues":         "Unknown cues %q, expected line or word",
+		"timings":      "Verse %v.%v has no %[4]v word timings in recitation %[3]v",
+	},
+}
+
+// errorText - localised error text, English one if the language has no own
+func errorText(languageID, key string) string {
+	if text, ok := errorTexts[languageID][key]; ok {
+		return text
+	}
+	return errorTexts["en"][key]
+}
+
+// Suggestion - link offered on a 404 page
+type Suggestion struct {
+	URL   string
+	Title string
+}
+
+// errorLanguage - language of an error page: the one in the route or the first path element if it is loaded, else the default
+func errorLanguage(r *http.Request) string {
+	languageID := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+	if _, ok := Corpora()[languageID]; ok {
+		return languageID
+	}
+	return defaultLangID
+}
+
+// renderError - renders the error page with the status; a failing template falls back to plain text
+func renderError(w http.ResponseWriter, r *http.Request, status int, languageID, heading, message string, suggestions []Suggestion) {
+	searchURL, err := router.Get("langSearch").URL("language", languageID, "search", pageSlug(languageID, "search"))
+	if err != nil {
+		http.Error(w, heading, status)
+		return
+	}
+	upURL, err := router.Get("langIndex").URL("language", languageID)
+	if err != nil {
+		http.Error(w, heading, status)
+		return
+	}
+	if status == http.StatusNotFound {
+		suggestions = append(suggestions, Suggestion{URL: upURL.String(), Title: errorText(languageID, "contents")})
+	}
+
+	fp := path.Join("templates", "error.html")
+	tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
+	if err != nil {
+		log.Printf("%s %s: %s", r.Method, r.URL, err)
+		http.Error(w, heading, status)
+		return
+	}
+
+	meta := PageMeta{
+		Title:    heading + " – " + localised(bookTitles, languageID),
+		Keywords: localised(keywords, languageID),
+		Robots:   "noindex",
+		Type:     "website",
+		Locale:   localised(ogLocales, languageID),
+		SiteName: localised(bookTitles, languageID),
+	}
+	data := map[string]interface{}{
+		"languageId":  languageID,
+		"meta":        meta,
+		"heading":     heading,
+		"message":     message,
+		"suggestions": suggestions,
+		"suggestText": errorText(languageID, "suggestions"),
+		"searchURL":   searchURL.String(),
+		"up":          template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String())),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tmpl.Execute(w, data); err != nil {
+		log.Printf("%s %s: %s", r.Method, r.URL, err)
+	}
+}
+
+// notFound - 404 page with the message and suggestions, in the language of the request
+func notFound(w http.ResponseWriter, r *http.Request, message string, suggestions []Suggestion) {
+	languageID := errorLanguage(r)
+	renderError(w, r, http.StatusNotFound, languageID, errorText(languageID, "notFound"), message, suggestions)
+}
+
+// pageNotFound - 404 page of a path which is no page
+func pageNotFound(w http.ResponseWriter, r *http.Request) {
+	notFound(w, r, fmt.Sprintf(errorText(errorLanguage(r), "page"), r.URL.Path), nil)
+}
+
+// badRequest - 400 page with the message, in the language of the request
+func badRequest(w http.ResponseWriter, r *http.Request, message string) {
+	languageID := errorLanguage(r)
+	renderError(w, r, http.StatusBadRequest, languageID, errorText(languageID, "badRequest"), message, nil)
+}
+
+// serverError - logs the error with the request and shows the 500 page without its details
+func serverError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %s", r.Method, r.URL, err)
+	languageID := errorLanguage(r)
+	renderError(w, r, http.StatusInternalServerError, languageID, errorText(languageID, "serverError"), errorText(languageID, "serverDetail"), nil)
+}
+
+// trackingWriter - ResponseWriter which records whether the response has been started
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *trackingWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *trackingWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap - the wrapped ResponseWriter, for http.ResponseController
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recoverPanics - middleware turning an unexpected panic of a handler into the 500 page, if the handler has not
+// started the response yet; http.ErrAbortHandler is passed on to abort the response
+func recoverPanics(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracked := &trackingWriter{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			log.Printf("panic: %v\n%s", recovered, debug.Stack())
+			if !tracked.written {
+				serverError(w, r, fmt.Errorf("panic: %v", recovered))
+			}
+		}()
+		handler.ServeHTTP(tracked, r)
+	})
+}
+
+// nearestVerse - the valid chapter and verse closest to the given numbers
+func nearestVerse(book *Book, chapterNum, verseNum int) (int, int) {
+	if chapterNum < 1 {
+		chapterNum = 1
+	} else if chapterNum > len(book.Chapters) {
+		chapterNum = len(book.Chapters)
+	}
+	if verses := len(book.Chapters[chapterNum-1].Verses); verseNum > verses {
+		verseNum = verses
+	} else if verseNum < 1 {
+		verseNum = 1
+	}
+	return chapterNum, verseNum
+}
+
+// verseSuggestions - links to the nearest valid verse and its chapter, for a 404 page of a missing chapter or verse;
+// none if their URLs cannot be built, the 404 page is still shown
+func verseSuggestions(languageID string, book *Book, chapterNum, verseNum int) []Suggestion {
+	chapterNum, verseNum = nearestVerse(book, chapterNum, verseNum)
+	verse := book.Chapters[chapterNum-1].Verses[verseNum-1]
+	verseURL, err := verseURL(languageID, book, chapterNum, verseNum)
+	if err != nil {
+		log.Printf("verse suggestions: %s", err)
+		return nil
+	}
+	chapterURL, err := router.Get("langChapter").URL("language", languageID, "chapter", strconv.Itoa(chapterNum))
+	if err != nil {
+		log.Printf("verse suggestions: %s", err)
+		return nil
+	}
+	return []Suggestion{
+		{URL: verseURL.String(), Title: fmt.Sprintf("BG %d.%s", chapterNum, verse.Ref())},
+		{URL: chapterURL.String(), Title: fmt.Sprintf("BG %d. %s", chapterNum, book.Chapters[chapterNum-1].Name)},
+	}
+}
+
+// versePathRe - paths which look like a chapter or verse: /lt/19, /2/99, /lt/2/80-85
+var versePathRe = regexp.MustCompile(`^/(?:([a-z]{2})/)?(\d+)(?:/(\d+)(?:-\d+)?)?/?$`)
+
+// NotFoundHandler - 404 page of the paths no route matches, with the nearest verse for the ones which look like a verse
+// of a loaded language or of the default one
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	languageID := errorLanguage(r)
+	book := Corpora()[languageID]
+	if match := versePathRe.FindStringSubmatch(r.URL.Path); match != nil && (match[1] == "" || match[1] == languageID) {
+		chapterNum, _ := strconv.Atoi(match[2])
+		if match[3] == "" {
+			notFound(w, r, fmt.Sprintf(errorText(languageID, "chapter"), match[2]), verseSuggestions(languageID, book, chapterNum, 1))
+			return
+		}
+		verseNum, _ := strconv.Atoi(match[3])
+		notFound(w, r, fmt.Sprintf(errorText(languageID, "verse"), match[2], match[3]), verseSuggestions(languageID, book, chapterNum, verseNum))
+		return
+	}
+	pageNotFound(w, r)
+}
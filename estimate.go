@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// Prosodic weights of the estimate, in mātrās: a light (laghu) syllable takes one, a heavy (guru) one two
+const (
+	laghuWeight   = 1
+	guruWeight    = 2
+	dandaPause    = 3 // pause at the danda "।" between the half-verses
+	verseEndPause = 3 // pause at the double danda "॥" ending the verse, after the last word
+)
+
+// longVowels - vowels which make a syllable heavy by themselves
+var longVowels = map[string]bool{"ā": true, "ī": true, "ū": true, "ṝ": true, "ḹ": true, "e": true, "ai": true, "o": true, "au": true}
+
+// wordWeights - prosodic weight of every indexed word. A syllable is heavy if its vowel is long, if an
+// anusvāra or visarga follows it or if two or more consonants follow it before the next vowel - also across
+// words, as the verse is recited continuously. A danda weighs as much as the pause it marks.
+func wordWeights(words [][]Word, parse func(string) []translit.Token) []int {
+	type letter struct {
+		word  int
+		token translit.Token
+	}
+	var weights []int
+	var letters []letter
+	for _, lineWords := range words {
+		for _, word := range lineWords {
+			if word.Index < 0 {
+				continue
+			}
+			weights = append(weights, 0)
+			if word.Text == "।" {
+				weights[word.Index] = dandaPause
+				continue
+			}
+			for _, token := range parse(word.Text) {
+				if token.IsLetter() {
+					letters = append(letters, letter{word.Index, token})
+				}
+			}
+		}
+	}
+
+	for i, l := range letters {
+		if l.token.Kind != translit.Vowel {
+			continue
+		}
+		weight := laghuWeight
+		if longVowels[l.token.Text] {
+			weight = guruWeight
+		} else {
+			consonants := 0
+			for _, next := range letters[i+1:] {
+				if next.token.Kind == translit.Modifier {
+					consonants = 2
+				}
+				if next.token.Kind != translit.Consonant {
+					break
+				}
+				consonants++
+			}
+			if consonants >= 2 {
+				weight = guruWeight
+			}
+		}
+		weights[l.word] += weight
+	}
+
+	// a word without a vowel (a stray consonant or sign) still takes some time
+	for i, weight := range weights {
+		if weight == 0 {
+			weights[i] = laghuWeight
+		}
+	}
+	return weights
+}
+
+// estimateTimings - word timings of a recitation lasting duration seconds, distributed over the words by their
+// weights and followed by the pause at the end of the verse; nil without words
+func estimateTimings(words [][]Word, parse func(string) []translit.Token, duration float32) []float32 {
+	weights := wordWeights(words, parse)
+	if len(weights) == 0 {
+		return nil
+	}
+	total := verseEndPause
+	for _, weight := range weights {
+		total += weight
+	}
+
+	timings := []float32{0}
+	elapsed := 0
+	for _, weight := range weights {
+		elapsed += weight
+		timings = append(timings, float32(elapsed)/float32(total)*duration)
+	}
+	return timings
+}
+
+// estimateCommand - "estimate [-duration seconds] [-file file.json] chapter.verse" subcommand: prints estimated word timings
+// of a verse in the corpus format, for editors to refine and paste. Exit code is 0, or 2 if the verse cannot be loaded.
+func estimateCommand(args []string) int {
+	flags := flag.NewFlagSet("estimate", flag.ExitOnError)
+	duration := flags.Float64("duration", 1, "duration of the recitation in seconds; timings are fractions of it by default")
+	file := flags.String("file", "", "corpus file, the "+defaultLangID+" corpus in "+textsDir+" by default")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s estimate [-duration seconds] [-file file.json] chapter.verse\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var chapterNum, verseNum int
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if _, err := fmt.Sscanf(flags.Arg(0), "%d.%d", &chapterNum, &verseNum); err != nil {
+		fmt.Fprintf(os.Stderr, "Verse %q is not chapter.verse: %s\n", flags.Arg(0), err)
+		return 2
+	}
+
+	if *file == "" {
+		corpusFiles, err := discoverCorpora(textsDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*file = corpusFiles[defaultLangID]
+	}
+	book, err := loadBook(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if chapterNum < 1 || chapterNum > len(book.Chapters) || verseNum < 1 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+		fmt.Fprintf(os.Stderr, "Verse %d.%d does not exist!\n", chapterNum, verseNum)
+		return 2
+	}
+	verse := book.Chapters[chapterNum-1].Verses[verseNum-1]
+
+	round := func(timings []float32) []float64 {
+		rounded := []float64{}
+		for _, t := range timings {
+			rounded = append(rounded, math.Floor(float64(t)*1000+0.5)/1000)
+		}
+		return rounded
+	}
+	out := json.NewEncoder(os.Stdout)
+	out.SetEscapeHTML(false)
+	out.Encode(map[string]interface{}{
+		"devanagari": round(estimateTimings(verse.DevanagariWords, translit.ParseDevanagari, float32(*duration))),
+		"iast":       round(estimateTimings(verse.IASTWords, translit.ParseIAST, float32(*duration))),
+	})
+	return 0
+}
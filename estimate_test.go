@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// iastLine - a line of indexed words; "॥" is the verse number marker without an index
+func iastLine(first int, texts ...string) []Word {
+	var words []Word
+	for i, text := range texts {
+		index := first + i
+		if text == "॥" {
+			index = -1
+		}
+		words = append(words, Word{Index: index, Text: text})
+	}
+	return words
+}
+
+func TestWordWeights(t *testing.T) {
+	tests := []struct {
+		words [][]Word
+		want  []int
+	}{
+		// long vowel, then a short one before a single consonant: guru + laghu
+		{[][]Word{iastLine(0, "rāma")}, []int{3}},
+		// a short vowel before a conjunct is heavy, also across the hyphen of a compound: dhar-ma-kṣe-tre
+		{[][]Word{iastLine(0, "dharma-kṣetre")}, []int{8}},
+		// anusvāra and visarga make the syllable heavy
+		{[][]Word{iastLine(0, "saṁjaya")}, []int{4}},
+		{[][]Word{iastLine(0, "duḥkham")}, []int{3}},
+		// a conjunct at the start of the next word makes the last syllable of the word heavy, a single consonant does not
+		{[][]Word{iastLine(0, "rāma", "kṣetre")}, []int{4, 4}},
+		{[][]Word{iastLine(0, "rāma", "kṛṣṇa")}, []int{3, 3}},
+		{[][]Word{iastLine(0, "rāma", "kuru")}, []int{3, 2}},
+		// a danda weighs its pause, the verse marker is not a word, a word without a vowel weighs a laghu
+		{[][]Word{iastLine(0, "rāma", "।"), iastLine(2, "k", "॥")}, []int{3, dandaPause, laghuWeight}},
+	}
+	for _, test := range tests {
+		if got := wordWeights(test.words, translit.ParseIAST); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("wordWeights(%v) = %v, want %v", test.words, got, test.want)
+		}
+	}
+}
+
+func TestEstimateTimings(t *testing.T) {
+	// weights 3, 3 and the danda's 3 plus the pause at the end of the verse: 12 mātrās in 6 seconds
+	words := [][]Word{iastLine(0, "rāma", "।"), iastLine(2, "rāma", "॥")}
+	want := []float32{0, 1.5, 3, 4.5}
+	if got := estimateTimings(words, translit.ParseIAST, 6); !reflect.DeepEqual(got, want) {
+		t.Errorf("estimateTimings = %v, want %v", got, want)
+	}
+	if got := estimateTimings(nil, translit.ParseIAST, 6); got != nil {
+		t.Errorf("estimateTimings without words = %v, want nil", got)
+	}
+}
+
+func TestTimedGroupWords(t *testing.T) {
+	iastTrack, _ := timingTrack("iast")
+	verses := []Verse{
+		{Num: 16, IASTWords: [][]Word{iastLine(0, "rāma", "।"), iastLine(2, "rāma", "॥")}},
+		{Num: 17, IASTWords: [][]Word{iastLine(0, "rāma", "॥")}},
+	}
+	starts := func(grouped [][][]Word) (indices []int, starts []float32) {
+		for _, lines := range grouped {
+			for _, line := range lines {
+				for _, word := range line {
+					if word.Index >= 0 {
+						indices, starts = append(indices, word.Index), append(starts, word.Start)
+					}
+				}
+			}
+		}
+		return indices, starts
+	}
+
+	// estimated over the whole group: the words follow one another, numbered through the group
+	grouped := timedGroupWords(verses, iastTrack, func(Verse) []float32 { return nil })
+	if len(grouped) != 2 || len(grouped[0]) != 2 || len(grouped[1]) != 1 {
+		t.Fatalf("lines of the verses %v", grouped)
+	}
+	indices, estimated := starts(grouped)
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(indices, want) {
+		t.Errorf("indices %v, want %v", indices, want)
+	}
+	for i := 1; i < len(estimated); i++ {
+		if estimated[i] <= estimated[i-1] {
+			t.Errorf("estimated starts %v do not follow one another", estimated)
+		}
+	}
+
+	// recorded timings of every verse are in seconds of the group's recording
+	recorded := map[int][]float32{16: {0, 1, 2, 3}, 17: {4, 5}}
+	_, got := starts(timedGroupWords(verses, iastTrack, func(verse Verse) []float32 { return recorded[verse.Num] }))
+	if want := []float32{0, 1, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded starts %v, want %v", got, want)
+	}
+
+	// without a recording the words are numbered but not timed
+	for _, lines := range timedGroupWords(verses, iastTrack, nil) {
+		for _, line := range lines {
+			for _, word := range line {
+				if word.Timed || word.Estimated {
+					t.Errorf("word %+v of no recording is timed", word)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MediaProblem - a recording file of a recitation which is missing, empty or not expected
+type MediaProblem struct {
+	Recitation string `json:"recitation"`
+	Format     string `json:"format,omitempty"`
+	Location   string `json:"location,omitempty"` // verse or verse group "2.13", "1.16-18"; none for an extra file
+	File       string `json:"file"`               // relative to the media directory
+	Problem    string `json:"problem"`            // "missing", "empty", "extra"
+}
+
+// Inventory - recording files of a recitation found in the media directory
+type Inventory struct {
+	Groups   int              // verses and verse groups of the book, one file each per format
+	Present  map[string]int   // non-empty files by format
+	Recorded []verseRange     // verse groups with a non-empty file in at least one format
+	Sizes    map[string]int64 // bytes of the non-empty files by name
+	Problems []MediaProblem
+}
+
+// takeInventory - compares the files in the recitation's directory under dir with the verse groups of the book
+func takeInventory(recitation *Recitation, book *Book, dir string) (Inventory, error) {
+	inventory := Inventory{Present: map[string]int{}, Sizes: map[string]int64{}}
+	recitationDir := filepath.Join(dir, filepath.FromSlash(recitation.Path))
+	infos, err := ioutil.ReadDir(recitationDir)
+	if err != nil && !os.IsNotExist(err) {
+		return inventory, err
+	}
+	sizes := map[string]int64{}
+	for _, info := range infos {
+		if !info.IsDir() {
+			sizes[info.Name()] = info.Size()
+		}
+	}
+
+	problem := func(format, location, name, kind string) {
+		inventory.Problems = append(inventory.Problems, MediaProblem{
+			Recitation: recitation.ID,
+			Format:     format,
+			Location:   location,
+			File:       filepath.ToSlash(filepath.Join(recitation.Path, name)),
+			Problem:    kind,
+		})
+	}
+
+	expected := map[string]bool{}
+	for _, chapter := range book.Chapters {
+		for verseNum := 1; verseNum <= len(chapter.Verses); verseNum = chapter.Verses[verseNum-1].To + 1 {
+			verse := chapter.Verses[verseNum-1]
+			location := fmt.Sprintf("%d.%s", chapter.Num, verse.Ref())
+			inventory.Groups++
+			recorded := false
+			for _, format := range recitation.Formats {
+				name := recitation.FileName(chapter.Num, verse.From, format)
+				expected[name] = true
+				size, ok := sizes[name]
+				switch {
+				case !ok:
+					problem(format, location, name, "missing")
+				case size == 0:
+					problem(format, location, name, "empty")
+				default:
+					inventory.Present[format]++
+					inventory.Sizes[name] = size
+					recorded = true
+				}
+			}
+			if recorded {
+				inventory.Recorded = append(inventory.Recorded, verseRange{Chapter: chapter.Num, From: verse.From, To: verse.To})
+			}
+		}
+	}
+
+	var extra []string
+	for name := range sizes {
+		if !expected[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		problem("", "", name, "extra")
+	}
+	return inventory, nil
+}
+
+// inventoryCommand - "media-inventory [-dir dir] [-recitation id] [-json] [-write]" subcommand: reports the missing,
+// empty and extra recording files of the recitations in a local media directory; with -write the verses found
+// in any format replace the recitations' verse lists in the catalogue, so that verse pages without a recording
+// have no player, and the file sizes are kept for the formats of every verse and the podcast enclosures.
+// Exit code is 0, 1 if a file is missing, empty or extra, 2 on a load error.
+func inventoryCommand(args []string) int {
+	flags := flag.NewFlagSet("media-inventory", flag.ExitOnError)
+	dir := flags.String("dir", "", "local media directory, the one of the media configuration by default")
+	recitationID := flags.String("recitation", "", "ID of the recitation in "+catalogueName+", all by default")
+	jsonOutput := flags.Bool("json", false, "print the problems as JSON lines")
+	write := flags.Bool("write", false, "write the recorded verses and file sizes of the recitations into "+catalogueName)
+	file := flags.String("file", "", "corpus file, the "+defaultLangID+" corpus in "+textsDir+" by default")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s media-inventory [-dir dir] [-recitation id] [-json] [-write] [-file file.json]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *dir == "" {
+		config, err := loadMediaConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if config.Dir == "" {
+			fmt.Fprintln(os.Stderr, "No local media directory: set -dir or $MEDIA_DIR")
+			return 2
+		}
+		*dir = config.Dir
+	}
+	if *file == "" {
+		corpusFiles, err := discoverCorpora(textsDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*file = corpusFiles[defaultLangID]
+	}
+	book, err := loadBook(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	catalogue, err := loadCatalogue(catalogueFile())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	inventoried := catalogue.Recitations
+	if *recitationID != "" {
+		recitation := catalogue.Recitation(*recitationID)
+		if recitation == nil {
+			fmt.Fprintf(os.Stderr, "No recitation %q in %s\n", *recitationID, catalogueFile())
+			return 2
+		}
+		inventoried = []*Recitation{recitation}
+	}
+
+	exitCode := 0
+	out := json.NewEncoder(os.Stdout)
+	for _, recitation := range inventoried {
+		inventory, err := takeInventory(recitation, book, *dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if len(inventory.Problems) > 0 {
+			exitCode = 1
+		}
+		recitation.Verses = verseRanges(inventory.Recorded, book)
+		recitation.Sizes = inventory.Sizes
+
+		if *jsonOutput {
+			for _, problem := range inventory.Problems {
+				out.Encode(problem)
+			}
+			continue
+		}
+		fmt.Printf("%s (%s), %s:\n", recitation.ID, recitation.Name(defaultLangID), filepath.Join(*dir, filepath.FromSlash(recitation.Path)))
+		for _, format := range recitation.Formats {
+			fmt.Printf("  %-4s %d of %d\n", format, inventory.Present[format], inventory.Groups)
+		}
+		fmt.Printf("  recorded: %d of %d\n", len(inventory.Recorded), inventory.Groups)
+		for _, problem := range inventory.Problems {
+			fmt.Printf("  %-7s  %-8s %s\n", problem.Problem, problem.Location, problem.File)
+		}
+	}
+
+	if *write {
+		if err := saveCatalogue(catalogue, catalogueFile()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s not written: %s\n", catalogueFile(), err)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "%s written\n", catalogueFile())
+	}
+	return exitCode
+}
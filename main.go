@@ -4,19 +4,33 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
-// BG is a top-level structure of the book
-var BG Book
-
 // defaultLangID - default to LT if no language specified
 var defaultLangID = "lt"
 
 var router *mux.Router
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validateCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check-iast" {
+		os.Exit(checkIASTCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "estimate" {
+		os.Exit(estimateCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "align" {
+		os.Exit(alignCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "media-inventory" {
+		os.Exit(inventoryCommand(os.Args[2:]))
+	}
+
 	port := os.Getenv("PORT")
 
 	if port == "" && len(os.Args) > 1 {
@@ -28,22 +42,59 @@ func main() {
 	}
 
 	loadJSON()
+	loadMedia()
+	routeKeywords = newRouteKeywords(languages())
+	loadRedirects()
+	go watchCorpora()
+
+	// Allowed languages are the ones with a loaded corpus, e.g. /{language:en|lt}
+	langPath := "/{language:" + strings.Join(languages(), "|") + "}"
 
 	router = mux.NewRouter()
 	router.HandleFunc("/", IndexHandler)
-	router.HandleFunc("/{language:en|lt}", LangIndexHandler).Name("langIndex")
+	router.HandleFunc(langPath, LangIndexHandler).Name("langIndex")
+
+	router.HandleFunc(langPath+"/{section:"+strings.Join(sectionSlugs(languages()), "|")+"}", LangSectionHandler).Name("langSection")
+
+	router.HandleFunc(langPath+"/{search:"+pageSlugPattern("search", languages())+"}", LangSearchHandler).Name("langSearch")
+
+	router.HandleFunc(langPath+"/{glossary:"+pageSlugPattern("glossary", languages())+"}", LangGlossaryHandler).Name("langGlossary")
+	router.HandleFunc(langPath+"/{glossary:"+pageSlugPattern("glossary", languages())+"}/{term}", LangGlossaryTermHandler).Name("langGlossaryTerm")
 
 	router.HandleFunc("/{chapter:\\d{1,2}}", ChapterHandler)
-	router.HandleFunc("/{language:en|lt}/{chapter:\\d{1,2}}", LangChapterHandler).Name("langChapter")
+	router.HandleFunc(langPath+"/{chapter:\\d{1,2}}", LangChapterHandler).Name("langChapter")
+	router.HandleFunc(langPath+"/{chapter:\\d{1,2}}.{format:m3u|xspf}", LangPlaylistHandler).Name("langChapterPlaylist")
+	router.HandleFunc(langPath+"/playlist.{format:m3u|xspf}", LangPlaylistHandler).Name("langPlaylist")
+	router.HandleFunc(langPath+"/{chapter:\\d{1,2}}.rss", LangPodcastHandler).Name("langChapterPodcast")
+	router.HandleFunc(langPath+"/podcast.rss", LangPodcastHandler).Name("langPodcast")
+
+	// verse is either a single verse number or a joined verse group: 13 or 16-18
+	router.HandleFunc("/{chapter:\\d{1,2}}/{verse:\\d{1,2}(?:-\\d{1,2})?}", ChapterVerseHandler)
+	router.HandleFunc(langPath+"/{chapter:\\d{1,2}}/{verse:\\d{1,2}(?:-\\d{1,2})?}", LangChapterVerseHandler).Name("langChapterVerse")
+	router.HandleFunc(langPath+"/{chapter:\\d{1,2}}/{verse:\\d{1,2}(?:-\\d{1,2})?}.{format:vtt|srt}", LangChapterVerseCaptionsHandler).Name("langChapterVerseCaptions")
 
-	router.HandleFunc("/{chapter:\\d{1,2}}/{verse:\\d{1,2}}", ChapterVerseHandler)
-	router.HandleFunc("/{language:en|lt}/{chapter:\\d{1,2}}/{verse:\\d{1,2}}", LangChapterVerseHandler).Name("langChapterVerse")
+	// Read-only JSON API
+	router.HandleFunc("/api/v1/recitations", APIRecitationsHandler).Name("apiRecitations")
+	router.HandleFunc("/api/v1"+langPath+"/chapters", APIChaptersHandler).Name("apiChapters")
+	router.HandleFunc("/api/v1"+langPath+"/chapters/{chapter:\\d{1,2}}", APIChapterHandler).Name("apiChapter")
+	router.HandleFunc("/api/v1"+langPath+"/chapters/{chapter:\\d{1,2}}/verses/{verse:\\d{1,2}}", APIChapterVerseHandler).Name("apiChapterVerse")
 
+	// Editors' pages, enabled with $ADMIN_PASSWORD
+	router.HandleFunc("/admin"+langPath+"/iast", adminOnly(AdminIASTHandler)).Name("adminIAST")
+
+	if media.Dir != "" {
+		router.PathPrefix(mediaPrefix).HandlerFunc(MediaHandler)
+	}
 	router.PathPrefix("/public/").Handler(http.StripPrefix("/public/", http.FileServer(http.Dir("public"))))
-	//TODO: favicon, robots.txt
+	router.HandleFunc("/go", GoHandler).Name("go")
+	router.HandleFunc("/robots.txt", RobotsHandler)
+	router.HandleFunc("/sitemap.xml", SitemapHandler).Name("sitemap")
+	router.HandleFunc(langPath+"/sitemap.xml", LangSitemapHandler).Name("langSitemap")
 	router.HandleFunc("/favicon.ico", func(res http.ResponseWriter, req *http.Request) {
 		http.ServeFile(res, req, "favicon.ico")
 	})
 
-	log.Fatal(http.ListenAndServe(":"+port, router))
+	router.NotFoundHandler = http.HandlerFunc(NotFoundHandler)
+
+	log.Fatal(http.ListenAndServe(":"+port, recoverPanics(normaliseURLs(router))))
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// mediaConfigFile - media configuration, overridden by $MEDIA_CONFIG
+const mediaConfigFile = "./media.json"
+
+// mediaPrefix - URL path the server serves local media files under
+const mediaPrefix = "/media/"
+
+// MediaConfig - where the recordings of the recitations are
+type MediaConfig struct {
+	BaseURL string `json:"baseUrl"` // e.g. "https://media.bhagavad-gita.lt"; served from Dir if empty
+	Dir     string `json:"dir"`     // local directory this server serves at /media/, "" - none
+}
+
+// media - the media configuration loaded at startup
+var media MediaConfig
+
+// mediaTypes - MIME types of the audio formats, for <source type> and the local file server
+var mediaTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"ogg":  "audio/ogg",
+	"opus": "audio/ogg; codecs=opus",
+	"m4a":  "audio/mp4",
+	"wav":  "audio/wav",
+}
+
+// loadMediaConfig - reads the media configuration; $MEDIA_BASE_URL and $MEDIA_DIR override its base URL and directory
+func loadMediaConfig() (MediaConfig, error) {
+	var config MediaConfig
+	filename := os.Getenv("MEDIA_CONFIG")
+	if filename == "" {
+		filename = mediaConfigFile
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("JSON unmarshalling of %s failed: %s", filename, err)
+	}
+
+	if baseURL, ok := os.LookupEnv("MEDIA_BASE_URL"); ok {
+		config.BaseURL = baseURL
+	}
+	if dir, ok := os.LookupEnv("MEDIA_DIR"); ok {
+		config.Dir = dir
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.BaseURL == "" && config.Dir == "" {
+		return config, fmt.Errorf("%s: neither baseUrl nor dir is set", filename)
+	}
+	if !secureBaseURL(config.BaseURL) {
+		return config, fmt.Errorf("%s: baseUrl %q must be an https:// or protocol-relative // URL, plain HTTP media do not play on HTTPS pages", filename, config.BaseURL)
+	}
+	return config, nil
+}
+
+// secureBaseURL - whether media under the base URL play on HTTPS pages: an https:// or protocol-relative URL,
+// or plain HTTP on the local host for development; "" for the local media directory
+func secureBaseURL(baseURL string) bool {
+	if baseURL == "" || strings.HasPrefix(baseURL, "https://") || strings.HasPrefix(baseURL, "//") {
+		return true
+	}
+	u, err := url.Parse(baseURL)
+	return err == nil && u.Scheme == "http" && (u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1")
+}
+
+// loadMedia - loads the media configuration or exits
+func loadMedia() {
+	config, err := loadMediaConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	media = config
+	if media.BaseURL == "" {
+		log.Printf("Media served from %s at %s", media.Dir, mediaPrefix)
+	}
+}
+
+// mediaURL - URL of a media file by its path relative to the base URL
+func (config MediaConfig) mediaURL(relPath string) string {
+	if config.BaseURL == "" {
+		return mediaPrefix + relPath
+	}
+	return config.BaseURL + "/" + relPath
+}
+
+// MediaHandler - serves the files of the local media directory with Range requests for seeking: /media/recitation/1/2-13.mp3
+func MediaHandler(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + strings.TrimPrefix(r.URL.Path, mediaPrefix))
+	file, err := os.Open(filepath.Join(media.Dir, filepath.FromSlash(name)))
+	if err != nil {
+		pageNotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		pageNotFound(w, r)
+		return
+	}
+	if mediaType, ok := mediaTypes[strings.TrimPrefix(path.Ext(name), ".")]; ok {
+		w.Header().Set("Content-Type", mediaType)
+	} else if mediaType := mime.TypeByExtension(path.Ext(name)); mediaType != "" {
+		w.Header().Set("Content-Type", mediaType)
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// PlaylistEntry - recording of a verse or verse group in a playlist
+type PlaylistEntry struct {
+	Title    string // "2.13", "1.16-18"
+	Album    string // chapter: "2. Gitos turinys glaustai"
+	Location string // absolute URL of the recording
+	Page     string // absolute URL of the verse page
+}
+
+// absoluteURL - the URL resolved against the request, for playlists opened outside the browser
+func absoluteURL(r *http.Request, u *url.URL) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return (&url.URL{Scheme: scheme, Host: r.Host}).ResolveReference(u).String()
+}
+
+// playlistEntries - recordings of the recitation of the chapters' verse groups, each in the first format it is
+// recorded in; verses without a recording are left out
+func playlistEntries(r *http.Request, languageID string, book *Book, chapters []Chapter, recitation *Recitation) ([]PlaylistEntry, error) {
+	var entries []PlaylistEntry
+	for _, chapter := range chapters {
+		for verseIdx := 0; verseIdx < len(chapter.Verses); verseIdx = chapter.Verses[verseIdx].To {
+			verse := chapter.Verses[verseIdx]
+			formats := recitation.VerseFormats(chapter.Num, verse.From)
+			if !recitation.Has(chapter.Num, verse.From) || len(formats) == 0 {
+				continue
+			}
+			audioURL, err := url.Parse(recitation.AudioURL(chapter.Num, verse.From, formats[0]))
+			if err != nil {
+				continue
+			}
+			pageURL, err := verseURL(languageID, book, chapter.Num, verse.Num)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, PlaylistEntry{
+				Title:    fmt.Sprintf("%d.%s", chapter.Num, verse.Ref()),
+				Album:    fmt.Sprintf("%d. %s", chapter.Num, chapter.Name),
+				Location: absoluteURL(r, audioURL),
+				Page:     absoluteURL(r, pageURL),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// m3uEscaper - line breaks would start a new M3U entry
+var m3uEscaper = strings.NewReplacer("\r", " ", "\n", " ")
+
+// writeM3U - extended M3U playlist of the entries
+func writeM3U(title, creator string, entries []PlaylistEntry) []byte {
+	var out bytes.Buffer
+	out.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&out, "#PLAYLIST:%s\n", m3uEscaper.Replace(title))
+	for _, entry := range entries {
+		fmt.Fprintf(&out, "#EXTINF:-1,%s - %s\n", m3uEscaper.Replace(creator), m3uEscaper.Replace(entry.Title))
+		fmt.Fprintf(&out, "#EXTALB:%s\n", m3uEscaper.Replace(entry.Album))
+		fmt.Fprintf(&out, "%s\n", entry.Location)
+	}
+	return out.Bytes()
+}
+
+// xspfPlaylist - XML Shareable Playlist Format, version 1
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version int         `xml:"version,attr"`
+	Title   string      `xml:"title"`
+	Creator string      `xml:"creator"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// xspfTrack - track of an XSPF playlist
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator"`
+	Album    string `xml:"album"`
+	Info     string `xml:"info"`
+}
+
+// writeXSPF - XSPF playlist of the entries
+func writeXSPF(title, creator string, entries []PlaylistEntry) ([]byte, error) {
+	playlist := xspfPlaylist{Version: 1, Title: title, Creator: creator, Tracks: []xspfTrack{}}
+	for _, entry := range entries {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location: entry.Location,
+			Title:    entry.Title,
+			Creator:  creator,
+			Album:    entry.Album,
+			Info:     entry.Page,
+		})
+	}
+	data, err := xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// playlistURL - URL of the playlist of a chapter, or of the whole book if chapterNum is 0, in the format (m3u, xspf)
+func playlistURL(languageID string, chapterNum int, format, recitationID string) (*url.URL, error) {
+	var playlistURL *url.URL
+	var err error
+	if chapterNum == 0 {
+		playlistURL, err = router.Get("langPlaylist").URL("language", languageID, "format", format)
+	} else {
+		playlistURL, err = router.Get("langChapterPlaylist").URL("language", languageID, "chapter", strconv.Itoa(chapterNum), "format", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	playlistURL.RawQuery = url.Values{"recitation": {recitationID}}.Encode()
+	return playlistURL, nil
+}
+
+// LangPlaylistHandler - playlist of the recordings of a recitation, of a chapter or the whole book:
+// /lt/2.m3u?recitation=1, /lt/playlist.xspf
+func LangPlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+
+	recitation := Recitations().Recitations[0]
+	if id := r.URL.Query().Get("recitation"); id != "" {
+		if recitation = Recitations().Recitation(id); recitation == nil {
+			badRequest(w, r, fmt.Sprintf(errorText(vars["language"], "recitation"), id))
+			return
+		}
+	}
+
+	title := localised(bookTitles, vars["language"])
+	chapters := book.Chapters[:]
+	if vars["chapter"] != "" {
+		chapterNum, _ := strconv.Atoi(vars["chapter"])
+		if chapterNum == 0 || chapterNum > len(book.Chapters) {
+			notFound(w, r, fmt.Sprintf(errorText(vars["language"], "chapter"), vars["chapter"]), verseSuggestions(vars["language"], book, chapterNum, 1))
+			return
+		}
+		chapters = chapters[chapterNum-1 : chapterNum]
+		title = fmt.Sprintf("%s. %d. %s", title, chapterNum, chapters[0].Name)
+	}
+
+	entries, err := playlistEntries(r, vars["language"], book, chapters, recitation)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if vars["format"] == "xspf" {
+		data, err := writeXSPF(title, recitation.Name(vars["language"]), entries)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xspf+xml; charset=utf-8")
+		w.Write(data)
+	} else {
+		w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+		w.Write(writeM3U(title, recitation.Name(vars["language"]), entries))
+	}
+}
+
+// nextRecorded - the next verse group of the chapter after the verse with a recording of the recitation, nil at the end
+func nextRecorded(chapter Chapter, verse Verse, recitation *Recitation) *Verse {
+	for verseIdx := verse.To; verseIdx < len(chapter.Verses); verseIdx = chapter.Verses[verseIdx].To {
+		if next := chapter.Verses[verseIdx]; recitation.Has(chapter.Num, next.From) {
+			return &next
+		}
+	}
+	return nil
+}
+
+// listenURL - verse page in the listen mode, which plays the recitation and goes on to the next recorded verse
+func listenURL(languageID string, book *Book, chapterNum, verseNum int, recitationID string) (*url.URL, error) {
+	listenURL, err := verseURL(languageID, book, chapterNum, verseNum)
+	if err != nil {
+		return nil, err
+	}
+	listenURL.RawQuery = url.Values{"listen": {"1"}, "recitation": {recitationID}}.Encode()
+	return listenURL, nil
+}
+
+// playlistLinks - links to the playlists of a chapter, or of the whole book if chapterNum is 0, in all formats
+// and to its podcast feed
+func playlistLinks(languageID string, chapterNum int, recitationID string) ([]template.HTML, error) {
+	var linksList []template.HTML
+	for _, format := range []string{"m3u", "xspf"} {
+		playlistURL, err := playlistURL(languageID, chapterNum, format, recitationID)
+		if err != nil {
+			return nil, err
+		}
+		linksList = append(linksList, template.HTML(fmt.Sprintf(`<a href="%s">%s</a>`, template.HTMLEscapeString(playlistURL.String()), strings.ToUpper(format))))
+	}
+	podcastURL, err := podcastURL(languageID, chapterNum, recitationID)
+	if err != nil {
+		return nil, err
+	}
+	linksList = append(linksList, template.HTML(fmt.Sprintf(`<a href="%s">Podcast</a>`, template.HTMLEscapeString(podcastURL.String()))))
+	return linksList, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// podcastEpoch - publication date of the first episode; the following ones are a minute apart in book order,
+// since the recordings have no dates of their own and podcast apps order episodes by date
+var podcastEpoch = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// rssFeed - RSS 2.0 document with the iTunes podcast extensions
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	ITunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel - the podcast
+type rssChannel struct {
+	Title       string          `xml:"title"`
+	Link        string          `xml:"link"`
+	Description string          `xml:"description"`
+	Language    string          `xml:"language"`
+	Author      string          `xml:"itunes:author"`
+	Summary     string          `xml:"itunes:summary"`
+	Type        string          `xml:"itunes:type"`
+	Explicit    string          `xml:"itunes:explicit"`
+	Category    rssCategory     `xml:"itunes:category"`
+	Image       *rssITunesImage `xml:"itunes:image,omitempty"`
+	Items       []rssItem       `xml:"item"`
+}
+
+// rssCategory - iTunes category with its subcategory
+type rssCategory struct {
+	Text        string       `xml:"text,attr"`
+	Subcategory *rssCategory `xml:"itunes:category,omitempty"`
+}
+
+// rssITunesImage - podcast artwork
+type rssITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// rssItem - episode, the recording of a verse or verse group
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	Episode     int          `xml:"itunes:episode"`
+	Season      int          `xml:"itunes:season,omitempty"`
+}
+
+// rssGUID - episode ID: the URL of its recording
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// rssEnclosure - the recording; Length is 0 if the file is not in the media inventory
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// podcastFeed - feed of the recorded verses of the chapters, each in the first format it is recorded in;
+// episodes are numbered within the chapter, which is their season in the feed of the whole book
+func podcastFeed(r *http.Request, languageID string, book *Book, chapters []Chapter, recitation *Recitation, title string) (rssFeed, error) {
+	indexURL, err := router.Get("langIndex").URL("language", languageID)
+	if err != nil {
+		return rssFeed{}, err
+	}
+	feed := rssFeed{
+		Version: "2.0",
+		ITunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        absoluteURL(r, indexURL),
+			Description: localised(bookDescriptions, languageID),
+			Language:    languageID,
+			Author:      recitation.Name(languageID),
+			Summary:     localised(bookDescriptions, languageID),
+			Type:        "serial",
+			Explicit:    "false",
+			Category:    rssCategory{Text: "Religion & Spirituality", Subcategory: &rssCategory{Text: "Hinduism"}},
+		},
+	}
+	if recitation.Image != "" {
+		imageURL, err := url.Parse(media.mediaURL(recitation.Image))
+		if err != nil {
+			return rssFeed{}, err
+		}
+		feed.Channel.Image = &rssITunesImage{Href: absoluteURL(r, imageURL)}
+	}
+
+	published := podcastEpoch
+	for _, chapter := range chapters {
+		episode := 0
+		for verseIdx := 0; verseIdx < len(chapter.Verses); verseIdx = chapter.Verses[verseIdx].To {
+			verse := chapter.Verses[verseIdx]
+			published = published.Add(time.Minute)
+			formats := recitation.VerseFormats(chapter.Num, verse.From)
+			if !recitation.Has(chapter.Num, verse.From) || len(formats) == 0 {
+				continue
+			}
+			format := formats[0]
+			episode++
+			audioURL, err := url.Parse(recitation.AudioURL(chapter.Num, verse.From, format))
+			if err != nil {
+				return rssFeed{}, err
+			}
+			pageURL, err := verseURL(languageID, book, chapter.Num, verse.Num)
+			if err != nil {
+				return rssFeed{}, err
+			}
+			item := rssItem{
+				Title:       fmt.Sprintf("%d. %s, %d.%s", chapter.Num, chapter.Name, chapter.Num, verse.Ref()),
+				Link:        absoluteURL(r, pageURL),
+				Description: string(chapter.Verses[verse.To-1].Translation),
+				GUID:        rssGUID{Value: absoluteURL(r, audioURL)},
+				PubDate:     published.Format(time.RFC1123Z),
+				Enclosure: rssEnclosure{
+					URL:    absoluteURL(r, audioURL),
+					Length: recitation.Sizes[recitation.FileName(chapter.Num, verse.From, format)],
+					Type:   mediaTypes[format],
+				},
+				Episode: episode,
+			}
+			if len(chapters) > 1 {
+				item.Season = chapter.Num
+			}
+			feed.Channel.Items = append(feed.Channel.Items, item)
+		}
+	}
+	return feed, nil
+}
+
+// podcastURL - URL of the podcast feed of a chapter, or of the whole book if chapterNum is 0
+func podcastURL(languageID string, chapterNum int, recitationID string) (*url.URL, error) {
+	var podcastURL *url.URL
+	var err error
+	if chapterNum == 0 {
+		podcastURL, err = router.Get("langPodcast").URL("language", languageID)
+	} else {
+		podcastURL, err = router.Get("langChapterPodcast").URL("language", languageID, "chapter", strconv.Itoa(chapterNum))
+	}
+	if err != nil {
+		return nil, err
+	}
+	podcastURL.RawQuery = url.Values{"recitation": {recitationID}}.Encode()
+	return podcastURL, nil
+}
+
+// LangPodcastHandler - podcast RSS feed of a recitation, of a chapter or the whole book: /lt/2.rss?recitation=1, /lt/podcast.rss
+func LangPodcastHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+
+	recitation := Recitations().Recitations[0]
+	if id := r.URL.Query().Get("recitation"); id != "" {
+		if recitation = Recitations().Recitation(id); recitation == nil {
+			badRequest(w, r, fmt.Sprintf(errorText(vars["language"], "recitation"), id))
+			return
+		}
+	}
+
+	title := localised(bookTitles, vars["language"])
+	chapters := book.Chapters[:]
+	if vars["chapter"] != "" {
+		chapterNum, _ := strconv.Atoi(vars["chapter"])
+		if chapterNum == 0 || chapterNum > len(book.Chapters) {
+			notFound(w, r, fmt.Sprintf(errorText(vars["language"], "chapter"), vars["chapter"]), verseSuggestions(vars["language"], book, chapterNum, 1))
+			return
+		}
+		chapters = chapters[chapterNum-1 : chapterNum]
+		title = fmt.Sprintf("%s. %d. %s", title, chapterNum, chapters[0].Name)
+	}
+
+	feed, err := podcastFeed(r, vars["language"], book, chapters, recitation, title)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+	w.Write([]byte("\n"))
+}
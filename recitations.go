@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// catalogueName - file of the recitation catalogue in textsDir, shared by all languages
+const catalogueName = "recitations.json"
+
+// TimingTrack - verse lines a recitation is timed against, word by word
+type TimingTrack struct {
+	ID    string // key of the timings in the catalogue: "devanagari", "iast"
+	Label string
+	Words func(v Verse) [][]Word
+	Parse func(string) []translit.Token
+}
+
+// timingTracks - tracks in the order of the verse page
+var timingTracks = []TimingTrack{
+	{"devanagari", "देवनागरी", func(v Verse) [][]Word { return v.DevanagariWords }, translit.ParseDevanagari},
+	{"iast", "IAST", func(v Verse) [][]Word { return v.IASTWords }, translit.ParseIAST},
+}
+
+// timingTrack - track by its ID
+func timingTrack(id string) (TimingTrack, bool) {
+	for _, track := range timingTracks {
+		if track.ID == id {
+			return track, true
+		}
+	}
+	return TimingTrack{}, false
+}
+
+// Recitation - recordings of the book by one reciter: {Path}/{chapter}-{verse}.{format} under the media base URL,
+// joined verse groups in the file of their first verse
+type Recitation struct {
+	ID       string                          `json:"id"`
+	Reciter  string                          `json:"reciter"`
+	Language string                          `json:"language"`        // of the recited text: "sa" for the verses, "lt" for a reading of the translation
+	Style    string                          `json:"style"`           // e.g. "giedojimas", "skaitymas"
+	Path     string                          `json:"path"`            // relative to the media base URL: "recitation/1"
+	Formats  []string                        `json:"formats"`         // file extensions in the order of preference: "mp3", "ogg"
+	Verses   []string                        `json:"verses"`          // recorded chapters "2", verses "2.13" and ranges "2.13-20"; all if null
+	Timings  map[string]map[string][]float32 `json:"timings"`         // word timings by verse "18.65" and track ID
+	Image    string                          `json:"image,omitempty"` // podcast artwork, relative to the media base URL
+	Sizes    map[string]int64                `json:"sizes,omitempty"` // bytes by FileName "2-13.mp3" of the files found by the media inventory
+
+	available []verseRange
+}
+
+// verseRange - verses From..To of a chapter
+type verseRange struct {
+	Chapter, From, To int
+}
+
+// verseRangeRe - entry of Recitation.Verses: "2", "2.13" or "2.13-20"
+var verseRangeRe = regexp.MustCompile(`^(\d{1,2})(?:\.(\d{1,2})(?:-(\d{1,2}))?)?$`)
+
+// anonymousRecitations - name of a recitation without a reciter by language, with its ID
+var anonymousRecitations = map[string]string{
+	"lt": "Įrašas %s",
+	"en": "Recording %s",
+}
+
+// Name - reciter, or the localised name of an anonymous recitation with its ID
+func (r *Recitation) Name(languageID string) string {
+	if r.Reciter != "" {
+		return r.Reciter
+	}
+	return fmt.Sprintf(localised(anonymousRecitations, languageID), r.ID)
+}
+
+// Has - whether the recitation has a recording of the verse
+func (r *Recitation) Has(chapterNum, verseNum int) bool {
+	if r.Verses == nil {
+		return true
+	}
+	for _, available := range r.available {
+		if available.Chapter == chapterNum && verseNum >= available.From && verseNum <= available.To {
+			return true
+		}
+	}
+	return false
+}
+
+// VerseTimings - recorded word timings of the verse for the track, nil if there are none
+func (r *Recitation) VerseTimings(chapterNum, verseNum int, trackID string) []float32 {
+	return r.Timings[fmt.Sprintf("%d.%d", chapterNum, verseNum)][trackID]
+}
+
+// GroupWords - words of the verses of a joined group for the track, numbered through the group, with the
+// recitation's timings, or ones estimated over the group's recording if it has none
+func (r *Recitation) GroupWords(chapterNum int, verses []Verse, track TimingTrack) [][][]Word {
+	return timedGroupWords(verses, track, func(verse Verse) []float32 {
+		return r.VerseTimings(chapterNum, verse.Num, track.ID)
+	})
+}
+
+// FileName - name of the recording file of a verse in the format, relative to Path: "2-13.mp3";
+// a joined verse group is recorded in the file of its first verse, 1.16-18 in "1-16.mp3"
+func (r *Recitation) FileName(chapterNum, verseNum int, format string) string {
+	return fmt.Sprintf("%d-%d.%s", chapterNum, verseNum, format)
+}
+
+// VerseFormats - formats the verse is recorded in, in the order of preference: the ones the media inventory found
+// a file of, or all Formats if it has not been taken
+func (r *Recitation) VerseFormats(chapterNum, verseNum int) []string {
+	if r.Sizes == nil {
+		return r.Formats
+	}
+	var formats []string
+	for _, format := range r.Formats {
+		if r.Sizes[r.FileName(chapterNum, verseNum, format)] > 0 {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// AudioURL - URL of the recording of a verse in the format; verseNum is the first verse of a joined group
+func (r *Recitation) AudioURL(chapterNum, verseNum int, format string) string {
+	return media.mediaURL(r.Path + "/" + r.FileName(chapterNum, verseNum, format))
+}
+
+// audioSources - <source> elements of the recording of a verse or verse group in the formats it is recorded in
+func audioSources(recitation *Recitation, chapterNum int, verse Verse) []template.HTML {
+	var sourcesList []template.HTML
+	for _, format := range recitation.VerseFormats(chapterNum, verse.From) {
+		sourcesList = append(sourcesList, template.HTML(fmt.Sprintf(`<source src="%s" type="%s" />`,
+			template.HTMLEscapeString(recitation.AudioURL(chapterNum, verse.From, format)), template.HTMLEscapeString(mediaTypes[format]))))
+	}
+	return sourcesList
+}
+
+// Catalogue - all recitations of the book
+type Catalogue struct {
+	Recitations []*Recitation `json:"recitations"`
+}
+
+// Recitations - currently loaded recitation catalogue. It must not be modified.
+func Recitations() *Catalogue {
+	return content.Load().(*Content).Recitations
+}
+
+// Recitation - recitation by its ID, nil if unknown
+func (c *Catalogue) Recitation(id string) *Recitation {
+	for _, recitation := range c.Recitations {
+		if recitation.ID == id {
+			return recitation
+		}
+	}
+	return nil
+}
+
+// ForVerse - recitations which have the verse, in catalogue order
+func (c *Catalogue) ForVerse(chapterNum, verseNum int) []*Recitation {
+	var verseRecitations []*Recitation
+	for _, recitation := range c.Recitations {
+		if recitation.Has(chapterNum, verseNum) {
+			verseRecitations = append(verseRecitations, recitation)
+		}
+	}
+	return verseRecitations
+}
+
+// Select - the recitation with the ID if it has the verse, else the first one which has it, else the first one
+func (c *Catalogue) Select(id string, chapterNum, verseNum int) *Recitation {
+	if recitation := c.Recitation(id); recitation != nil && recitation.Has(chapterNum, verseNum) {
+		return recitation
+	}
+	if verseRecitations := c.ForVerse(chapterNum, verseNum); len(verseRecitations) > 0 {
+		return verseRecitations[0]
+	}
+	return c.Recitations[0]
+}
+
+// catalogueFile - path of the recitation catalogue
+func catalogueFile() string {
+	return filepath.Join(textsDir, catalogueName)
+}
+
+// loadCatalogue - reads a recitation catalogue; malformed entries fail the load
+func loadCatalogue(filename string) (*Catalogue, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var catalogue Catalogue
+	if err := json.Unmarshal(data, &catalogue); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of %s failed: %s", filename, err)
+	}
+	if len(catalogue.Recitations) == 0 {
+		return nil, fmt.Errorf("%s: no recitations", filename)
+	}
+
+	ids := map[string]bool{}
+	for _, recitation := range catalogue.Recitations {
+		if recitation.ID == "" || ids[recitation.ID] {
+			return nil, fmt.Errorf("%s: missing or repeated recitation id %q", filename, recitation.ID)
+		}
+		ids[recitation.ID] = true
+		for _, format := range recitation.Formats {
+			if _, ok := mediaTypes[format]; !ok {
+				return nil, fmt.Errorf("%s: recitation %s has unknown format %q", filename, recitation.ID, format)
+			}
+		}
+		for _, verses := range recitation.Verses {
+			match := verseRangeRe.FindStringSubmatch(verses)
+			if match == nil {
+				return nil, fmt.Errorf("%s: recitation %s has verses %q, expected 2, 2.13 or 2.13-20", filename, recitation.ID, verses)
+			}
+			available := verseRange{From: 1, To: 99}
+			available.Chapter, _ = strconv.Atoi(match[1])
+			if match[2] != "" {
+				available.From, _ = strconv.Atoi(match[2])
+				available.To = available.From
+			}
+			if match[3] != "" {
+				available.To, _ = strconv.Atoi(match[3])
+			}
+			recitation.available = append(recitation.available, available)
+		}
+	}
+	return &catalogue, nil
+}
+
+// scalarArrayRe - JSON array of numbers or strings spread over lines by json.MarshalIndent
+var scalarArrayRe = regexp.MustCompile(`\[\n\s*([^\[\]{}]*?)\n\s*\]`)
+
+// scalarSeparatorRe - separator of the items of such an array
+var scalarSeparatorRe = regexp.MustCompile(`,\n\s*`)
+
+// saveCatalogue - writes the recitation catalogue with arrays of timings and formats kept on one line each,
+// to a temporary file first which must load back before it replaces the catalogue
+func saveCatalogue(catalogue *Catalogue, filename string) error {
+	data, err := json.MarshalIndent(catalogue, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = scalarArrayRe.ReplaceAllFunc(data, func(array []byte) []byte {
+		items := scalarSeparatorRe.ReplaceAll(scalarArrayRe.FindSubmatch(array)[1], []byte(", "))
+		return append(append([]byte("["), items...), ']')
+	})
+	if err := ioutil.WriteFile(filename+".tmp", append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if _, err := loadCatalogue(filename + ".tmp"); err != nil {
+		os.Remove(filename + ".tmp")
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// verseRanges - Recitation.Verses entries of the ranges, which are in the order of the book:
+// adjoining ranges are joined and complete chapters listed as "2"
+func verseRanges(ranges []verseRange, book *Book) []string {
+	var joined []verseRange
+	for _, r := range ranges {
+		if last := len(joined) - 1; last >= 0 && joined[last].Chapter == r.Chapter && joined[last].To+1 == r.From {
+			joined[last].To = r.To
+		} else {
+			joined = append(joined, r)
+		}
+	}
+	entries := []string{}
+	for _, r := range joined {
+		switch {
+		case r.From == 1 && r.To == len(book.Chapters[r.Chapter-1].Verses):
+			entries = append(entries, strconv.Itoa(r.Chapter))
+		case r.From == r.To:
+			entries = append(entries, fmt.Sprintf("%d.%d", r.Chapter, r.From))
+		default:
+			entries = append(entries, fmt.Sprintf("%d.%d-%d", r.Chapter, r.From, r.To))
+		}
+	}
+	return entries
+}
+
+// validateCatalogue - checks the timings of the recitations against the verses of the book
+func validateCatalogue(catalogue *Catalogue, book *Book) []Violation {
+	var violations []Violation
+	report := func(location, rule, severity, format string, args ...interface{}) {
+		violations = append(violations, Violation{Location: location, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, recitation := range catalogue.Recitations {
+		var locations []string
+		for location := range recitation.Timings {
+			locations = append(locations, location)
+		}
+		sort.Strings(locations)
+
+		for _, location := range locations {
+			var chapterNum, verseNum int
+			fmt.Sscanf(location, "%d.%d", &chapterNum, &verseNum)
+			if chapterNum < 1 || chapterNum > len(book.Chapters) || verseNum < 1 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+				report(location, "timings-verse", "warning", "recitation %s has timings of a verse which does not exist", recitation.ID)
+				continue
+			}
+			verse := book.Chapters[chapterNum-1].Verses[verseNum-1]
+
+			// Timings hold a start time for every word plus the end time of the last word;
+			// mismatching ones are not used for the highlighting
+			var trackIDs []string
+			for trackID := range recitation.Timings[location] {
+				trackIDs = append(trackIDs, trackID)
+			}
+			sort.Strings(trackIDs)
+			for _, trackID := range trackIDs {
+				timings := recitation.Timings[location][trackID]
+				track, ok := timingTrack(trackID)
+				if !ok {
+					report(location, "timings-track", "warning", "recitation %s has timings of an unknown track %q", recitation.ID, trackID)
+					continue
+				}
+				if words := wordCount(track.Words(verse)); len(timings) != words+1 {
+					report(location, track.ID+"-timings-count", "warning", "recitation %s: %d timings for %d words, expected %d", recitation.ID, len(timings), words, words+1)
+				}
+				if !sort.SliceIsSorted(timings, func(i, j int) bool { return timings[i] < timings[j] }) {
+					report(location, track.ID+"-timings-order", "warning", "recitation %s: timings are not monotonic: %v", recitation.ID, timings)
+				}
+			}
+		}
+	}
+	return violations
+}
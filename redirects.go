@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// redirectsFile - map of old URLs to the current ones, overridden by $REDIRECTS_FILE; optional
+const redirectsFile = "./redirects.json"
+
+// redirects - old paths, e.g. of the previous site, and their current URLs, loaded at startup
+var redirects = map[string]string{}
+
+// unnormalisedPrefixes - paths of files served as they are, case included
+var unnormalisedPrefixes = []string{"/public/", mediaPrefix}
+
+// numberSegmentRe - path element of chapter or verse numbers, possibly zero-padded: 02, 013, 016-018, 02.m3u
+var numberSegmentRe = regexp.MustCompile(`^(\d+)(?:-(\d+))?(\.[a-z0-9]+)?$`)
+
+// leadingSlashesRe - slashes and backslashes at the start of a path; more than one would make the redirect
+// a protocol-relative URL of another host: //evil.com
+var leadingSlashesRe = regexp.MustCompile(`^[/\\]+`)
+
+// fixedRouteKeywords - path elements of the routes which are the same in every language
+var fixedRouteKeywords = []string{
+	"api", "v1", "chapters", "verses", "recitations", "admin", "iast", "go",
+	"playlist.m3u", "playlist.xspf", "podcast.rss", "sitemap.xml", "robots.txt", "favicon.ico",
+}
+
+// routeKeywords - path elements of the routes in lower case, set at startup by newRouteKeywords; other elements,
+// e.g. glossary terms, keep their case
+var routeKeywords = map[string]bool{}
+
+// newRouteKeywords - route path elements of the languages: their IDs, front matter and page slugs and the fixed ones
+func newRouteKeywords(languageIDs []string) map[string]bool {
+	keywords := map[string]bool{}
+	for _, keyword := range fixedRouteKeywords {
+		keywords[keyword] = true
+	}
+	for _, slug := range sectionSlugs(languageIDs) {
+		keywords[slug] = true
+	}
+	for _, languageID := range languageIDs {
+		keywords[languageID] = true
+		for page := range pageSlugs["en"] {
+			keywords[pageSlug(languageID, page)] = true
+		}
+	}
+	return keywords
+}
+
+// loadRedirects - loads the redirect map if there is one; its keys are normalised like the request paths
+func loadRedirects() {
+	filename := os.Getenv("REDIRECTS_FILE")
+	if filename == "" {
+		filename = redirectsFile
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	var fileRedirects map[string]string
+	if err := json.Unmarshal(data, &fileRedirects); err != nil {
+		log.Fatalf("JSON unmarshalling of %s failed: %s", filename, err)
+	}
+	for from, to := range fileRedirects {
+		if !strings.HasPrefix(from, "/") {
+			log.Fatalf("%s: redirect from %q must be a path starting with /", filename, from)
+		}
+		if !strings.HasPrefix(to, "/") && !strings.HasPrefix(to, "http://") && !strings.HasPrefix(to, "https://") {
+			log.Fatalf("%s: redirect to %q must be a path or an http(s) URL", filename, to)
+		}
+		redirects[from] = to
+		redirects[normalisePath(from)] = to
+	}
+	log.Printf("loaded %d redirects from %s", len(fileRedirects), filename)
+}
+
+// trimZeros - number without leading zeros: 013 -> 13
+func trimZeros(number string) string {
+	if trimmed := strings.TrimLeft(number, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+// normalisePath - canonical form of a path: a single leading slash, route keywords in lower case, no trailing slash,
+// numbers without leading zeros (/LT/02/013/ -> /lt/2/13); files under unnormalisedPrefixes are left as they are
+func normalisePath(urlPath string) string {
+	urlPath = leadingSlashesRe.ReplaceAllString(urlPath, "/")
+	for _, prefix := range unnormalisedPrefixes {
+		if strings.HasPrefix(urlPath, prefix) {
+			return urlPath
+		}
+	}
+	if urlPath != "/" {
+		urlPath = strings.TrimRight(urlPath, "/")
+		if urlPath == "" {
+			urlPath = "/"
+		}
+	}
+	segments := strings.Split(urlPath, "/")
+	for i, segment := range segments {
+		if lower := strings.ToLower(segment); routeKeywords[lower] {
+			segments[i] = lower
+		} else if match := numberSegmentRe.FindStringSubmatch(lower); match != nil {
+			segments[i] = trimZeros(match[1])
+			if match[2] != "" {
+				segments[i] += "-" + trimZeros(match[2])
+			}
+			segments[i] += match[3]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// normaliseURLs - middleware redirecting the old paths of the redirect map and the non-canonical forms of paths
+// with 301; the query is kept
+func normaliseURLs(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		target, ok := redirects[r.URL.Path]
+		if !ok {
+			target, ok = redirects[normalisePath(r.URL.Path)]
+		}
+		if !ok {
+			if normalised := normalisePath(r.URL.Path); normalised != r.URL.Path {
+				target, ok = (&url.URL{Path: normalised}).String(), true
+			}
+		}
+		if !ok || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+			target = fmt.Sprintf("%s?%s", target, r.URL.RawQuery)
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestNormalisePath(t *testing.T) {
+	routeKeywords = newRouteKeywords([]string{"en", "lt"})
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "/"},
+		{"//", "/"},
+		{"/lt", "/lt"},
+		{"/LT/", "/lt"},
+		{"/LT/02/013/", "/lt/2/13"},
+		{"/lt/1/016-018", "/lt/1/16-18"},
+		{"/lt/02.M3U", "/lt/2.m3u"},
+		{"/lt/02/013.vtt", "/lt/2/13.vtt"},
+		{"/lt/0", "/lt/0"},
+		{"/lt/00", "/lt/0"},
+		{"/lt/Zodynas/Dharma", "/lt/zodynas/Dharma"},
+		{"/EN/Glossary/Kṛṣṇa/", "/en/glossary/Kṛṣṇa"},
+		{"/LT/Pratarme", "/lt/pratarme"},
+		{"/API/V1/LT/Chapters/02", "/api/v1/lt/chapters/2"},
+		{"/lt/Podcast.RSS", "/lt/podcast.rss"},
+		{"//evil.com/", "/evil.com"},
+		{"//Evil.com", "/Evil.com"},
+		{"///evil.com", "/evil.com"},
+		{"/\\evil.com", "/evil.com"},
+		{"//public/x", "/public/x"},
+		{"/lt/2a", "/lt/2a"},
+		{"/public/Images/01.PNG", "/public/Images/01.PNG"},
+		{"/media/Recitations/02-013.mp3", "/media/Recitations/02-013.mp3"},
+	}
+	for _, test := range tests {
+		if got := normalisePath(test.path); got != test.want {
+			t.Errorf("normalisePath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package reference parses free-form Bhagavad-gita verse references, as people write them,
+// into chapter and verse numbers.
+//
+// The book name is optional, the chapter may be a Roman numeral and the numbers may be labelled
+// with Lithuanian or English words, in either order:
+//
+//	reference.Parse("BG 2.13")                // {Chapter: 2, From: 13, To: 13}
+//	reference.Parse("Bg. 2:13")               // {Chapter: 2, From: 13, To: 13}
+//	reference.Parse("II.13")                  // {Chapter: 2, From: 13, To: 13}
+//	reference.Parse("2 skyrius 13 posmas")    // {Chapter: 2, From: 13, To: 13}
+//	reference.Parse("18.65-66")               // {Chapter: 18, From: 65, To: 66}
+//	reference.Parse("Bhagavad-gītā, XVIII")   // {Chapter: 18}
+//	reference.Parse("ch 2 v 13")              // {Chapter: 2, From: 13, To: 13}
+//
+// A "v" between the chapter and the verse number abbreviates verse; anywhere else it is the Roman numeral 5,
+// so "V.5" and "v 5" are verse 5.5.
+//
+// Parse checks the form of a reference only; whether the chapter and verses exist is up to the caller.
+package reference
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Reference - a chapter, a verse or a range of verses of a chapter
+type Reference struct {
+	Chapter int `json:"chapter"`
+	From    int `json:"from,omitempty"` // first verse, 0 for the whole chapter
+	To      int `json:"to,omitempty"`   // last verse, From for a single verse
+}
+
+// IsChapter - whether the reference is to the whole chapter
+func (ref Reference) IsChapter() bool {
+	return ref.From == 0
+}
+
+// String - canonical form of the reference: 2, 2.13, 18.65-66
+func (ref Reference) String() string {
+	switch {
+	case ref.IsChapter():
+		return strconv.Itoa(ref.Chapter)
+	case ref.From == ref.To:
+		return fmt.Sprintf("%d.%d", ref.Chapter, ref.From)
+	}
+	return fmt.Sprintf("%d.%d-%d", ref.Chapter, ref.From, ref.To)
+}
+
+// role - what a number of a reference is
+type role int
+
+const (
+	unlabelled role = iota
+	chapter
+	verse
+)
+
+// labels - words marking the number before or after them as a chapter or verse, in lower case
+var labels = map[string]role{
+	"skyrius": chapter, "skyriaus": chapter, "skyriuje": chapter, "skyr": chapter, "sk": chapter,
+	"chapter": chapter, "chap": chapter, "ch": chapter,
+	"posmas": verse, "posmo": verse, "posmai": verse, "posmų": verse, "posmuose": verse, "posm": verse,
+	"tekstas": verse, "teksto": verse, "tekstai": verse,
+	"verse": verse, "verses": verse, "vs": verse, "text": verse, "texts": verse, "śloka": verse, "sloka": verse,
+}
+
+// bookNames - words of the book name, skipped at the start of a reference: BG, B.G., Bhagavad-gītā, Gīta
+var bookNames = map[string]bool{
+	"bg": true, "b": true, "g": true, "bhagavad": true, "bhagavat": true,
+	"gita": true, "gītā": true, "gīta": true, "gitā": true, "gītos": true, "gitos": true,
+}
+
+// tokenRe - numbers, words and dashes of a reference; anything else separates them
+var tokenRe = regexp.MustCompile(`\d+|\p{L}+|[-–—]`)
+
+// romanRe - a valid Roman numeral in lower case
+var romanRe = regexp.MustCompile(`^m{0,3}(cm|cd|d?c{0,3})(xc|xl|l?x{0,3})(ix|iv|v?i{0,3})$`)
+
+// romanValues - values of the Roman digits
+var romanValues = map[rune]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100, 'd': 500, 'm': 1000}
+
+// verseAbbreviation - "v" of "ch 2 v 13", not among the labels as it is also a Roman numeral
+const verseAbbreviation = "v"
+
+// roman - value of a Roman numeral in lower case, false if the word is not one
+func roman(word string) (int, bool) {
+	if word == "" || !romanRe.MatchString(word) {
+		return 0, false
+	}
+	value := 0
+	digits := []rune(word)
+	for i, digit := range digits {
+		if i+1 < len(digits) && romanValues[digit] < romanValues[digits[i+1]] {
+			value -= romanValues[digit]
+		} else {
+			value += romanValues[digit]
+		}
+	}
+	return value, true
+}
+
+// parseNumber - value of an Arabic or Roman numeral, false if the token is not one
+func parseNumber(token string) (int, bool) {
+	if value, err := strconv.Atoi(token); err == nil {
+		return value, true
+	}
+	return roman(token)
+}
+
+// group - a number or a range of numbers with its role
+type group struct {
+	role     role
+	from, to int
+}
+
+// Parse - parses a reference to a chapter (2, II), a verse (2.13, 2:13, II.13, 2 skyrius 13 posmas)
+// or a range of verses (18.65-66, 18 skyriaus 65–66 posmai, ch 18 v 65-66), optionally preceded by the book name
+func Parse(s string) (Reference, error) {
+	tokens := tokenRe.FindAllString(strings.ToLower(s), -1)
+	for len(tokens) > 0 && (bookNames[tokens[0]] || isDash(tokens[0])) {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return Reference{}, fmt.Errorf("no chapter in reference %q", s)
+	}
+
+	var groups []group
+	pending := unlabelled // label before the next number
+	inRange := false      // a dash after the last number
+	afterNumber := false  // the previous token is a number
+	for i, token := range tokens {
+		if isDash(token) {
+			if !afterNumber || groups[len(groups)-1].from != groups[len(groups)-1].to {
+				return Reference{}, fmt.Errorf("misplaced dash in reference %q", s)
+			}
+			inRange, afterNumber = true, false
+			continue
+		}
+		if label, ok := labels[token]; ok {
+			if pending != unlabelled {
+				return Reference{}, fmt.Errorf("%q is not followed by a number in reference %q", token, s)
+			}
+			if last := len(groups) - 1; afterNumber && groups[last].role == unlabelled {
+				groups[last].role = label
+			} else {
+				pending = label
+			}
+			afterNumber = false
+			continue
+		}
+		if token == verseAbbreviation && afterNumber && len(groups) == 1 && i+1 < len(tokens) {
+			if _, ok := parseNumber(tokens[i+1]); ok {
+				pending, afterNumber = verse, false
+				continue
+			}
+		}
+
+		number, ok := parseNumber(token)
+		if !ok {
+			return Reference{}, fmt.Errorf("unknown word %q in reference %q", token, s)
+		}
+		if number == 0 {
+			return Reference{}, fmt.Errorf("number 0 in reference %q", s)
+		}
+		if inRange {
+			last := len(groups) - 1
+			if number < groups[last].from {
+				return Reference{}, fmt.Errorf("range %d-%d is backwards in reference %q", groups[last].from, number, s)
+			}
+			groups[last].to = number
+			inRange = false
+		} else {
+			groups = append(groups, group{role: pending, from: number, to: number})
+			pending = unlabelled
+		}
+		afterNumber = true
+	}
+	if inRange || pending != unlabelled {
+		return Reference{}, fmt.Errorf("incomplete reference %q", s)
+	}
+	if len(groups) > 2 {
+		return Reference{}, fmt.Errorf("too many numbers in reference %q, expected a chapter and a verse", s)
+	}
+
+	// the labelled numbers take their roles, the other ones the remaining ones in order: chapter, verse
+	var chapterGroup, verseGroup *group
+	for i := range groups {
+		switch groups[i].role {
+		case chapter:
+			if chapterGroup != nil {
+				return Reference{}, fmt.Errorf("two chapters in reference %q", s)
+			}
+			chapterGroup = &groups[i]
+		case verse:
+			if verseGroup != nil {
+				return Reference{}, fmt.Errorf("two verses in reference %q", s)
+			}
+			verseGroup = &groups[i]
+		}
+	}
+	for i := range groups {
+		if groups[i].role != unlabelled {
+			continue
+		}
+		if chapterGroup == nil {
+			chapterGroup = &groups[i]
+		} else {
+			verseGroup = &groups[i]
+		}
+	}
+	if chapterGroup == nil {
+		return Reference{}, fmt.Errorf("no chapter in reference %q", s)
+	}
+	if chapterGroup.from != chapterGroup.to {
+		return Reference{}, fmt.Errorf("chapter ranges are not supported, reference %q", s)
+	}
+
+	ref := Reference{Chapter: chapterGroup.from}
+	if verseGroup != nil {
+		ref.From, ref.To = verseGroup.from, verseGroup.to
+	}
+	return ref, nil
+}
+
+// isDash - whether the token is a hyphen or a dash of a range
+func isDash(token string) bool {
+	return token == "-" || token == "–" || token == "—"
+}
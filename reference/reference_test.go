@@ -0,0 +1,81 @@
+package reference
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Reference
+	}{
+		{"BG 2.13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"Bg. 2:13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"bg2.13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"B.G. 18.65–66", Reference{Chapter: 18, From: 65, To: 66}},
+		{"18.65-66", Reference{Chapter: 18, From: 65, To: 66}},
+		{"II.13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"Bhagavad-gītā, XVIII", Reference{Chapter: 18}},
+		{"2", Reference{Chapter: 2}},
+		{"2 skyrius 13 posmas", Reference{Chapter: 2, From: 13, To: 13}},
+		{"skyrius 2, posmas 13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"posmas 13, 2 skyrius", Reference{Chapter: 2, From: 13, To: 13}},
+		{"18 skyriaus 65–66 posmai", Reference{Chapter: 18, From: 65, To: 66}},
+		{"ch. 4 text 7", Reference{Chapter: 4, From: 7, To: 7}},
+		{"Chapter 2, verses 13-14", Reference{Chapter: 2, From: 13, To: 14}},
+		{"ch 2 v 13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"2 v. 13", Reference{Chapter: 2, From: 13, To: 13}},
+		{"ch 18 v 65-66", Reference{Chapter: 18, From: 65, To: 66}},
+		{"V.5", Reference{Chapter: 5, From: 5, To: 5}},
+		{"v 5", Reference{Chapter: 5, From: 5, To: 5}},
+		{"ch v", Reference{Chapter: 5}},
+		{"IV.v", Reference{Chapter: 4, From: 5, To: 5}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.s)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", test.s, err)
+		} else if got != test.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.s, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"BG",
+		"0",
+		"0.1",
+		"2.0",
+		"2.14-13",
+		"1.2.3",
+		"2-3",
+		"2.13-",
+		"2.13-14-15",
+		"skyrius",
+		"skyrius 2 posmas",
+		"2 skyrius 3 skyrius",
+		"posmas 13",
+		"foo 2.13",
+		"IIII.1",
+	} {
+		if ref, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", s, ref)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		ref  Reference
+		want string
+	}{
+		{Reference{Chapter: 2}, "2"},
+		{Reference{Chapter: 2, From: 13, To: 13}, "2.13"},
+		{Reference{Chapter: 18, From: 65, To: 66}, "18.65-66"},
+	}
+	for _, test := range tests {
+		if got := test.ref.String(); got != test.want {
+			t.Errorf("%+v.String() = %q, want %q", test.ref, got, test.want)
+		}
+	}
+}
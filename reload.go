@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// reloadInterval - how often the texts directory is checked for changed corpora
+var reloadInterval = 5 * time.Second
+
+// corporaFingerprint - names, sizes and modification times of the corpus files and the recitation catalogue;
+// changes whenever one of them is edited
+func corporaFingerprint(dir string) string {
+	corpusFiles, err := discoverCorpora(dir)
+	if err != nil {
+		return ""
+	}
+	files := []string{filepath.Join(dir, catalogueName)}
+	for _, file := range corpusFiles {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	fingerprint := ""
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fingerprint += fmt.Sprintf("%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint
+}
+
+// watchCorpora - reloads the corpora when files in the texts directory change or on SIGHUP
+func watchCorpora() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(reloadInterval)
+
+	fingerprint := corporaFingerprint(textsDir)
+	for {
+		select {
+		case <-hup:
+			log.Printf("SIGHUP: reloading corpora")
+		case <-ticker.C:
+			if corporaFingerprint(textsDir) == fingerprint {
+				continue
+			}
+			log.Printf("Corpora in %s changed: reloading", textsDir)
+		}
+		fingerprint = reloadCorpora()
+	}
+}
+
+// reloadCorpora - loads all corpora and the recitation catalogue in the background and swaps in the ones
+// that load and validate, together. A corpus or catalogue failing to load keeps its old content.
+// Returns the fingerprint of the files as they were before loading, so that edits during the load
+// are picked up by the next check.
+func reloadCorpora() string {
+	fingerprint := corporaFingerprint(textsDir)
+	corpusFiles, err := discoverCorpora(textsDir)
+	if err != nil {
+		log.Printf("Reload failed, keeping the old corpora: %s", err)
+		return fingerprint
+	}
+
+	current := Corpora()
+	books := map[string]*Book{}
+	for lang, book := range current {
+		books[lang] = book
+	}
+
+	for lang, file := range corpusFiles {
+		if _, ok := current[lang]; !ok {
+			// Language routes are set up at startup
+			log.Printf("[%s] new corpus %s will be served after restart", lang, file)
+			continue
+		}
+		book, err := loadCorpus(lang, file)
+		if err != nil {
+			log.Printf("[%s] reload failed, keeping the old content: %s", lang, err)
+			continue
+		}
+		books[lang] = book
+	}
+
+	catalogue, err := loadRecitations(books[defaultLangID])
+	if err != nil {
+		log.Printf("Reload of %s failed, keeping the old recitations: %s", catalogueName, err)
+		catalogue = Recitations()
+	}
+	content.Store(&Content{Corpora: books, Recitations: catalogue})
+	return fingerprint
+}
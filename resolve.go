@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bhakterija/bhagavad-gita.lt/reference"
+)
+
+// resolveReference - URL of the chapter or verse page of a parsed reference in the book; a range of verses
+// goes to the page of its first verse. The error is the localised 404 message of a missing chapter or verse.
+func resolveReference(languageID string, book *Book, ref reference.Reference) (*url.URL, error) {
+	if ref.Chapter > len(book.Chapters) {
+		return nil, fmt.Errorf(errorText(languageID, "chapter"), ref.Chapter)
+	}
+	if ref.IsChapter() {
+		return router.Get("langChapter").URL("language", languageID, "chapter", strconv.Itoa(ref.Chapter))
+	}
+	if ref.To > len(book.Chapters[ref.Chapter-1].Verses) {
+		return nil, fmt.Errorf(errorText(languageID, "verse"), ref.Chapter, ref.To)
+	}
+	return verseURL(languageID, book, ref.Chapter, ref.From)
+}
+
+// GoHandler - redirects a free-form reference to its page: /go?ref=BG+2.13, /go?ref=II.13&lang=en;
+// &format=json returns the parsed reference and its URL instead
+func GoHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	asJSON := query.Get("format") == "json"
+	refText := strings.TrimSpace(query.Get("ref"))
+
+	languageID := defaultLangID
+	if lang := query.Get("lang"); lang != "" {
+		languageID = lang
+	}
+	book, ok := Corpora()[languageID]
+	if !ok {
+		if asJSON {
+			writeJSONError(w, http.StatusBadRequest, "unknown language %q", languageID)
+			return
+		}
+		badRequest(w, r, fmt.Sprintf(errorText(defaultLangID, "language"), languageID))
+		return
+	}
+
+	ref, err := reference.Parse(refText)
+	if err != nil {
+		if asJSON {
+			writeJSONError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		renderError(w, r, http.StatusBadRequest, languageID, errorText(languageID, "badReference"),
+			fmt.Sprintf(errorText(languageID, "reference"), refText), nil)
+		return
+	}
+
+	refURL, err := resolveReference(languageID, book, ref)
+	if err != nil {
+		if asJSON {
+			writeJSONError(w, http.StatusNotFound, "%s", err)
+			return
+		}
+		renderError(w, r, http.StatusNotFound, languageID, errorText(languageID, "notFound"), err.Error(),
+			verseSuggestions(languageID, book, ref.Chapter, ref.From))
+		return
+	}
+
+	if asJSON {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"ref":       refText,
+			"reference": ref,
+			"canonical": "BG " + ref.String(),
+			"url":       absoluteURL(r, refURL),
+		})
+		return
+	}
+	http.Redirect(w, r, refURL.String(), http.StatusFound)
+}
@@ -4,73 +4,345 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"path"
 	"strconv"
+	"strings"
 
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
 	"github.com/gorilla/mux"
 )
 
-//TODO: title & keywords by language
-var pageTitle = "Bhagavad-Gita As It Is. Translation and commentaries by HDG A.C.Bhaktivedanta Swami Prabhupada"
-var pageKeywords = "Bhagavad Gita As It Is, Bhagavad-gītā, Bhagavad Gita, gita, ISKCON, Prabhupada, A.C. Bhaktivedanta Swami"
+// Section - front matter page preceding the chapters
+type Section struct {
+	Key   string // Book.Section key
+	Slug  string // URL path element: /lt/ivadas
+	Title string
+}
+
+// sections - front matter sections by language in reading order; the last one is followed by verse 1.1
+var sections = map[string][]Section{
+	"lt": {
+		{Key: "preface", Slug: "pratarme", Title: "Pratarmė"},
+		{Key: "introduction", Slug: "ivadas", Title: "Įvadas"},
+	},
+	"en": {
+		{Key: "preface", Slug: "preface", Title: "Preface"},
+		{Key: "introduction", Slug: "introduction", Title: "Introduction"},
+	},
+}
+
+// langSections - front matter sections of a language, English ones if the language has no own
+func langSections(languageID string) []Section {
+	if langSections, ok := sections[languageID]; ok {
+		return langSections
+	}
+	return sections["en"]
+}
+
+// bookSections - front matter sections of a language which have paragraphs in its corpus; the empty ones
+// are not linked, in the reading order or in the sitemap
+func bookSections(languageID string) []Section {
+	var published []Section
+	for _, section := range langSections(languageID) {
+		if len(Corpora()[languageID].Section(section.Key)) > 0 {
+			published = append(published, section)
+		}
+	}
+	return published
+}
+
+// sectionByKey - front matter section with paragraphs of a language by its Book.Section key
+func sectionByKey(languageID, key string) (Section, bool) {
+	for _, section := range bookSections(languageID) {
+		if section.Key == key {
+			return section, true
+		}
+	}
+	return Section{}, false
+}
+
+// sectionURLs - URL builder of the front matter section by its key in every language, for the hreflang alternates
+func sectionURLs(key string) func(languageID string) (*url.URL, error) {
+	return func(languageID string) (*url.URL, error) {
+		if section, ok := sectionByKey(languageID, key); ok {
+			return router.Get("langSection").URL("language", languageID, "section", section.Slug)
+		}
+		return nil, nil
+	}
+}
+
+// sectionSlugs - all front matter slugs of the given languages for the route pattern: pratarme|ivadas
+func sectionSlugs(languageIDs []string) []string {
+	var slugs []string
+	for _, languageID := range languageIDs {
+		for _, section := range langSections(languageID) {
+			slugs = append(slugs, section.Slug)
+		}
+	}
+	return slugs
+}
+
+// pageSlugs - localised URL path elements of other pages by language: /lt/paieska
+var pageSlugs = map[string]map[string]string{
+	"lt": {"search": "paieska", "glossary": "zodynas"},
+	"en": {"search": "search", "glossary": "glossary"},
+}
+
+// pageSlug - URL path element of a page in a language, English one if the language has no own
+func pageSlug(languageID, page string) string {
+	if slug, ok := pageSlugs[languageID][page]; ok {
+		return slug
+	}
+	return pageSlugs["en"][page]
+}
+
+// pageSlugPattern - route pattern matching the page slugs of the given languages: paieska|search
+func pageSlugPattern(page string, languageIDs []string) string {
+	var slugs []string
+	seen := map[string]bool{}
+	for _, languageID := range languageIDs {
+		if slug := pageSlug(languageID, page); !seen[slug] {
+			slugs = append(slugs, slug)
+			seen[slug] = true
+		}
+	}
+	return strings.Join(slugs, "|")
+}
 
 // IndexHandler - default route "/" handler - redirect to /+defaultLangID
 func IndexHandler(w http.ResponseWriter, r *http.Request) {
 	url, err := router.Get("langIndex").URL("language", defaultLangID)
 	if err != nil {
-		panic(err)
+		serverError(w, r, err)
+		return
 	}
 	http.Redirect(w, r, url.String(), 301)
 }
 
+// langBook - the corpus of the language in the route. The language route pattern only admits loaded corpora.
+func langBook(r *http.Request) *Book {
+	return Corpora()[mux.Vars(r)["language"]]
+}
+
+// verseURL - URL of a verse page; verses of a joined group share the URL of the group, e.g. /lt/1/16-18
+func verseURL(languageID string, book *Book, chapterNum, verseNum int) (*url.URL, error) {
+	verse := book.Chapters[chapterNum-1].Verses[verseNum-1]
+	return router.Get("langChapterVerse").URL("language", languageID, "chapter", strconv.Itoa(chapterNum), "verse", verse.Ref())
+}
+
+// alternateChapterURL - URL of a chapter page in the corpus of a language, for the hreflang alternates and
+// the sitemap; nil if that corpus has no verses of the chapter
+func alternateChapterURL(languageID string, chapterNum int) (*url.URL, error) {
+	if chapterNum < 1 || len(Corpora()[languageID].Chapters[chapterNum-1].Verses) == 0 {
+		return nil, nil
+	}
+	return router.Get("langChapter").URL("language", languageID, "chapter", strconv.Itoa(chapterNum))
+}
+
+// alternateVerseURL - URL of a verse page in the corpus of a language, for the hreflang alternates and the sitemap:
+// the page of the verse group of that corpus, which may join other verses; nil if that corpus has no such verse
+func alternateVerseURL(languageID string, chapterNum, verseNum int) (*url.URL, error) {
+	book := Corpora()[languageID]
+	if chapterNum < 1 || verseNum < 1 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+		return nil, nil
+	}
+	return verseURL(languageID, book, chapterNum, verseNum)
+}
+
 // LangIndexHandler - handles root+languageId: /lt/
 func LangIndexHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	book := langBook(r)
 	fp := path.Join("templates", "toc.html")
-	tmpl, err := template.ParseFiles(fp)
+	tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
+	// construct front matter list, sections without paragraphs as plain titles
+	var sectionsList []template.HTML
+	for _, section := range langSections(vars["language"]) {
+		if len(book.Section(section.Key)) == 0 {
+			sectionsList = append(sectionsList, template.HTML(fmt.Sprintf(`<td></td><td>%s</td>`, template.HTMLEscapeString(section.Title))))
+			continue
+		}
+		sectionURL, err := router.Get("langSection").URL("language", vars["language"], "section", section.Slug)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sectionsList = append(sectionsList, template.HTML(fmt.Sprintf(`<td></td><td><a href="%s">%s</a></td>`, sectionURL.String(), section.Title)))
+	}
+
 	// construct chapters list
 	var chaptersList []template.HTML
-	for _, chapter := range BG.Chapters {
+	var chaptersLD []interface{}
+	for _, chapter := range book.Chapters {
 		chapterURL, err := router.Get("langChapter").URL("language", vars["language"], "chapter", strconv.Itoa(chapter.Num))
 		if err != nil {
-			panic(err)
+			serverError(w, r, err)
+			return
 		}
 		numHref := template.HTML(fmt.Sprintf(`<a href="%s">%v</a>`, chapterURL.String(), chapter.Num))
 		nameHref := template.HTML(fmt.Sprintf(`<a href="%s">%v</a>`, chapterURL.String(), chapter.Name))
 		chaptersList = append(chaptersList, "<td>"+numHref+"</td><td>"+nameHref+"</td>")
+		chaptersLD = append(chaptersLD, map[string]interface{}{"@type": "Chapter", "position": chapter.Num, "name": fmt.Sprintf("%d. %s", chapter.Num, chapter.Name), "url": absoluteURL(r, chapterURL)})
+	}
+
+	meta, err := newPageMeta(r, vars["language"], localised(bookTitles, vars["language"]), localised(bookDescriptions, vars["language"]), func(languageID string) (*url.URL, error) {
+		return router.Get("langIndex").URL("language", languageID)
+	})
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	meta.Type = "book"
+	bookLD, err := bookJSONLD(r, vars["language"])
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	bookLD["hasPart"] = chaptersLD
+	if err := meta.setJSONLD(bookLD); err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	searchURL, err := router.Get("langSearch").URL("language", vars["language"], "search", pageSlug(vars["language"], "search"))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	glossaryURL, err := router.Get("langGlossary").URL("language", vars["language"], "glossary", pageSlug(vars["language"], "glossary"))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	playlists, err := playlistLinks(vars["language"], 0, Recitations().Recitations[0].ID)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"languageId":  vars["language"],
+		"meta":        meta,
+		"searchURL":   searchURL.String(),
+		"glossaryURL": glossaryURL.String(),
+		"sections":    sectionsList,
+		"chapters":    chaptersList,
+		"playlists":   playlists,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		serverError(w, r, err)
+	}
+}
+
+// LangSectionHandler - handles front matter pages: /lt/pratarme, /lt/ivadas
+func LangSectionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+
+	langSections := bookSections(vars["language"])
+	sectionIdx := -1
+	for i, section := range langSections {
+		if section.Slug == vars["section"] {
+			sectionIdx = i
+		}
+	}
+
+	if sectionIdx < 0 {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "section"), vars["section"]), nil)
+		return
+	}
+	section := langSections[sectionIdx]
+
+	fp := path.Join("templates", "section.html")
+	tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	// Create navigation arrows: sections are followed by verse 1.1
+	var prevHref, nextHref, upHref template.HTML
+	if sectionIdx > 0 {
+		prevURL, urlErr := router.Get("langSection").URL("language", vars["language"], "section", langSections[sectionIdx-1].Slug)
+		if urlErr != nil {
+			serverError(w, r, urlErr)
+			return
+		}
+		prevHref = template.HTML(fmt.Sprintf(`<a href="%s">&lt;&lt;</a>`, prevURL.String()))
+	} else {
+		prevHref = `&lt;&lt;`
+	}
+
+	var nextURL *url.URL
+	if sectionIdx < len(langSections)-1 {
+		nextURL, err = router.Get("langSection").URL("language", vars["language"], "section", langSections[sectionIdx+1].Slug)
+	} else {
+		nextURL, err = verseURL(vars["language"], book, 1, 1)
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	nextHref = template.HTML(fmt.Sprintf(`<a href="%s">&gt;&gt;</a>`, nextURL.String()))
+
+	upURL, urlErr := router.Get("langIndex").URL("language", vars["language"])
+	if urlErr != nil {
+		serverError(w, r, urlErr)
+		return
+	}
+	upHref = template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String()))
+
+	description := localised(bookDescriptions, vars["language"])
+	for _, paragraph := range book.Section(section.Key) {
+		if text := excerpt(plainText(paragraph), 160); text != "" {
+			description = text
+			break
+		}
+	}
+	meta, err := newPageMeta(r, vars["language"], section.Title+" – "+localised(bookTitles, vars["language"]), description, sectionURLs(section.Key))
+	if err != nil {
+		serverError(w, r, err)
+		return
 	}
 
 	data := map[string]interface{}{
-		"languageId": vars["language"],
-		"title":      pageTitle,
-		"keywords":   pageKeywords,
-		"chapters":   chaptersList,
+		"languageId":   vars["language"],
+		"meta":         meta,
+		"sectionTitle": section.Title,
+		"paragraphs":   book.Section(section.Key),
+		"next":         nextHref,
+		"prev":         prevHref,
+		"up":           upHref,
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		serverError(w, r, err)
 	}
 }
 
 // ChapterHandler - handles route where only chapter number is specified: /18/
 func ChapterHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	book := Corpora()[defaultLangID]
 	chapterNum, _ := strconv.Atoi(vars["chapter"])
 
-	if chapterNum == 0 || chapterNum > len(BG.Chapters) {
-		fmt.Fprintf(w, "Chapter %v does not exist!\n", vars["chapter"])
-		//TODO: redirect
+	if chapterNum == 0 || chapterNum > len(book.Chapters) {
+		notFound(w, r, fmt.Sprintf(errorText(defaultLangID, "chapter"), vars["chapter"]), verseSuggestions(defaultLangID, book, chapterNum, 1))
 	} else {
 		url, err := router.Get("langChapter").URL("language", defaultLangID, "chapter", vars["chapter"])
 		if err != nil {
-			panic(err)
+			serverError(w, r, err)
+			return
 		}
-		//fmt.Fprintf(w, "%v. %s\n", chapterNum, BG.Chapters[chapterNum-1].Name)
+		//fmt.Fprintf(w, "%v. %s\n", chapterNum, book.Chapters[chapterNum-1].Name)
 		http.Redirect(w, r, url.String(), 301)
 	}
 }
@@ -79,19 +351,19 @@ func ChapterHandler(w http.ResponseWriter, r *http.Request) {
 func LangChapterHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	//fmt.Fprintf(w, "LangChapterHandler: %v<br>", vars)
+	book := langBook(r)
 	chapterNum, _ := strconv.Atoi(vars["chapter"])
 
-	if chapterNum < 1 || chapterNum > len(BG.Chapters) {
-		fmt.Fprintf(w, "Chapter %v does not exist!\n", vars["chapter"])
-		//TODO: redirect
+	if chapterNum < 1 || chapterNum > len(book.Chapters) {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "chapter"), vars["chapter"]), verseSuggestions(vars["language"], book, chapterNum, 1))
 	} else {
-		chapter := BG.Chapters[chapterNum-1]
-		// fmt.Fprintf(w, "[%s] %v. %s\n", vars["language"], chapterNum, BG.Chapters[chapterNum-1].Name)
+		chapter := book.Chapters[chapterNum-1]
+		// fmt.Fprintf(w, "[%s] %v. %s\n", vars["language"], chapterNum, book.Chapters[chapterNum-1].Name)
 
 		fp := path.Join("templates", "chapter.html")
-		tmpl, err := template.ParseFiles(fp)
+		tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			serverError(w, r, err)
 			return
 		}
 
@@ -100,7 +372,8 @@ func LangChapterHandler(w http.ResponseWriter, r *http.Request) {
 		if chapter.PrevChapter > 0 {
 			prevURL, prevErr := router.Get("langChapter").URL("language", vars["language"], "chapter", strconv.Itoa(chapter.PrevChapter))
 			if prevErr != nil {
-				panic(err)
+				serverError(w, r, prevErr)
+				return
 			}
 			prevHref = template.HTML(fmt.Sprintf(`<a href="%s">&lt;&lt;</a>`, prevURL.String()))
 		} else {
@@ -110,7 +383,8 @@ func LangChapterHandler(w http.ResponseWriter, r *http.Request) {
 		if chapter.NextChapter > 0 {
 			nextURL, nextErr := router.Get("langChapter").URL("language", vars["language"], "chapter", strconv.Itoa(chapter.NextChapter))
 			if nextErr != nil {
-				panic(err)
+				serverError(w, r, nextErr)
+				return
 			}
 			nextHref = template.HTML(fmt.Sprintf(`<a href="%s">&gt;&gt;</a>`, nextURL.String()))
 		} else {
@@ -119,27 +393,69 @@ func LangChapterHandler(w http.ResponseWriter, r *http.Request) {
 
 		upURL, upErr := router.Get("langIndex").URL("language", vars["language"])
 		if upErr != nil {
-			panic(err)
+			serverError(w, r, upErr)
+			return
 		}
 		upHref = template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String()))
 
-		// construct verses list
+		// construct verses list, one row per verse group
 		var versesList []template.HTML
-		for _, verse := range chapter.Verses {
-			verseURL, err := router.Get("langChapterVerse").URL("language", vars["language"], "chapter", strconv.Itoa(chapter.Num), "verse", strconv.Itoa(verse.Num))
+		for verseIdx := 0; verseIdx < len(chapter.Verses); verseIdx = chapter.Verses[verseIdx].To {
+			verse := chapter.Verses[verseIdx]
+			verseURL, err := verseURL(vars["language"], book, chapter.Num, verse.Num)
 			if err != nil {
-				panic(err)
+				serverError(w, r, err)
+				return
 			}
-			verseNumHref := template.HTML(fmt.Sprintf(`<a href="%s">%v.%v</a>`, verseURL.String(), chapter.Num, verse.Num))
+			translation := chapter.Verses[verse.To-1].Translation
+			verseNumHref := template.HTML(fmt.Sprintf(`<a href="%s">%v.%v</a>`, verseURL.String(), chapter.Num, verse.Ref()))
 			// verseIASTHref := template.HTML(fmt.Sprintf(`<a href="%s">%v</a>`, verseURL.String(), strings.Join(verse.IAST, " | ")))
-			verseTranslationHref := template.HTML(fmt.Sprintf(`<a href="%s">%v</a>`, verseURL.String(), verse.Translation))
+			verseTranslationHref := template.HTML(fmt.Sprintf(`<a href="%s">%v</a>`, verseURL.String(), translation))
 			// versesList = append(versesList, "<td rowspan=\"2\" valign=\"top\">"+verseNumHref+"</td><td>"+verseIASTHref+"</td></tr> <tr><td>"+verseTranslationHref+"</td></tr>")
 			versesList = append(versesList, "<td valign=\"top\">"+verseNumHref+"</td><td>"+verseTranslationHref+"</td></tr>")
 		}
+		// listen mode from the first verse with a recording, and playlists of the chapter
+		recitation := preferredRecitation(w, r, chapter.Num, 1)
+		var listenHref string
+		if first := nextRecorded(chapter, Verse{}, recitation); first != nil {
+			listenURL, err := listenURL(vars["language"], book, chapter.Num, first.Num, recitation.ID)
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			listenHref = listenURL.String()
+		}
+
+		meta, err := newPageMeta(r, vars["language"], fmt.Sprintf("BG %d. %s", chapter.Num, chapter.Name),
+			fmt.Sprintf("%s, %d. %s: %s", localised(bookTitles, vars["language"]), chapter.Num, chapter.Name, excerpt(plainText(chapter.Verses[chapter.Verses[0].To-1].Translation), 120)),
+			func(languageID string) (*url.URL, error) {
+				return alternateChapterURL(languageID, chapter.Num)
+			})
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		meta.Type = "article"
+		chapterLD, err := chapterJSONLD(r, vars["language"], chapter)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		if err := meta.setJSONLD(chapterLD); err != nil {
+			serverError(w, r, err)
+			return
+		}
+		playlists, err := playlistLinks(vars["language"], chapter.Num, recitation.ID)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+
 		data := map[string]interface{}{
 			"languageId":  vars["language"],
-			"title":       pageTitle,
-			"keywords":    pageKeywords,
+			"meta":        meta,
+			"listenURL":   listenHref,
+			"playlists":   playlists,
 			"chapterNum":  chapter.Num,
 			"chapterName": chapter.Name,
 			"verses":      versesList,
@@ -149,62 +465,146 @@ func LangChapterHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err := tmpl.Execute(w, data); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			serverError(w, r, err)
 		}
 	}
 }
 
-// ChapterVerseHandler - handles route where chapter number and verse number are specified: /02/13/
+// ChapterVerseHandler - handles route where chapter number and verse number are specified: /02/13/ or /01/16-18/
 func ChapterVerseHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	book := Corpora()[defaultLangID]
 	chapterNum, _ := strconv.Atoi(vars["chapter"])
-	verseNum, _ := strconv.Atoi(vars["verse"])
-
-	if chapterNum == 0 || chapterNum > len(BG.Chapters) {
-		fmt.Fprintf(w, "Chapter %v does not exist!\n", vars["chapter"])
-		//TODO: redirect
-	} else if verseNum == 0 || verseNum > len(BG.Chapters[chapterNum-1].Verses) {
-		fmt.Fprintf(w, "Verse %v.%v does not exist!\n", vars["chapter"], vars["verse"])
-		//TODO: redirect
+	verseNum, _ := parseVerseRef(vars["verse"])
+
+	if chapterNum == 0 || chapterNum > len(book.Chapters) {
+		notFound(w, r, fmt.Sprintf(errorText(defaultLangID, "chapter"), vars["chapter"]), verseSuggestions(defaultLangID, book, chapterNum, verseNum))
+	} else if verseNum == 0 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+		notFound(w, r, fmt.Sprintf(errorText(defaultLangID, "verse"), vars["chapter"], vars["verse"]), verseSuggestions(defaultLangID, book, chapterNum, verseNum))
 	} else {
-		url, err := router.Get("langChapterVerse").URL("language", defaultLangID, "chapter", vars["chapter"], "verse", vars["verse"])
+		url, err := verseURL(defaultLangID, book, chapterNum, verseNum)
 		if err != nil {
-			panic(err)
+			serverError(w, r, err)
+			return
 		}
-		//fmt.Fprintf(w, "%v. %s\n", chapterNum, BG.Chapters[chapterNum-1].Name)
+		//fmt.Fprintf(w, "%v. %s\n", chapterNum, book.Chapters[chapterNum-1].Name)
 		http.Redirect(w, r, url.String(), 301)
 	}
 }
 
-// LangChapterVerseHandler - handles route where language, chapter number and verse number are specified: /lt/02/13/
+// Cookies remembering the reader's ?script= choice, one for each kind of scheme
+const (
+	indicScriptCookie = "script"
+	romanScriptCookie = "roman"
+)
+
+// preferredSchemes - Brahmic script and Roman scheme of the verse lines: ?script= if given (and remembered
+// in a cookie for the following pages), else the remembered ones, else Devanagari and IAST
+func preferredSchemes(w http.ResponseWriter, r *http.Request) (indic, roman translit.Scheme) {
+	indic, roman = translit.Devanagari, translit.IAST
+	if cookie, err := r.Cookie(indicScriptCookie); err == nil {
+		if scheme, ok := translit.ParseScheme(cookie.Value); ok && !scheme.IsRoman() {
+			indic = scheme
+		}
+	}
+	if cookie, err := r.Cookie(romanScriptCookie); err == nil {
+		if scheme, ok := translit.ParseScheme(cookie.Value); ok && scheme.IsRoman() {
+			roman = scheme
+		}
+	}
+
+	scheme, ok := translit.ParseScheme(r.URL.Query().Get("script"))
+	if !ok {
+		return indic, roman
+	}
+	cookieName := indicScriptCookie
+	if scheme.IsRoman() {
+		roman = scheme
+		cookieName = romanScriptCookie
+	} else {
+		indic = scheme
+	}
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Value: string(scheme), Path: "/", MaxAge: 365 * 24 * 60 * 60})
+	return indic, roman
+}
+
+// recitationCookie - cookie remembering the reader's ?recitation= choice
+const recitationCookie = "recitation"
+
+// preferredRecitation - recitation of the verse: ?recitation= if given (and remembered in a cookie), else the
+// remembered one, else the first one which has a recording of the verse
+func preferredRecitation(w http.ResponseWriter, r *http.Request, chapterNum, verseNum int) *Recitation {
+	id := r.URL.Query().Get("recitation")
+	if id != "" && Recitations().Recitation(id) != nil {
+		http.SetCookie(w, &http.Cookie{Name: recitationCookie, Value: id, Path: "/", MaxAge: 365 * 24 * 60 * 60})
+	} else if cookie, err := r.Cookie(recitationCookie); err == nil {
+		id = cookie.Value
+	}
+	return Recitations().Select(id, chapterNum, verseNum)
+}
+
+// schemeSelector - links to the schemes, the current one in bold
+func schemeSelector(schemes []translit.Scheme, current translit.Scheme) []template.HTML {
+	var schemesList []template.HTML
+	for _, s := range schemes {
+		if s == current {
+			schemesList = append(schemesList, template.HTML(fmt.Sprintf(`<b>%s</b>`, template.HTMLEscapeString(s.Name()))))
+		} else {
+			schemesList = append(schemesList, template.HTML(fmt.Sprintf(`<a href="?script=%s">%s</a>`, s, template.HTMLEscapeString(s.Name()))))
+		}
+	}
+	return schemesList
+}
+
+// LangChapterVerseHandler - handles route where language, chapter number and verse number are specified: /lt/02/13/ or /lt/01/16-18/
 func LangChapterVerseHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	book := langBook(r)
 	chapterNum, _ := strconv.Atoi(vars["chapter"])
-	verseNum, _ := strconv.Atoi(vars["verse"])
-
-	if chapterNum == 0 || chapterNum > len(BG.Chapters) {
-		fmt.Fprintf(w, "Chapter %v does not exist!\n", vars["chapter"])
-		//TODO: redirect
-	} else if verseNum == 0 || verseNum > len(BG.Chapters[chapterNum-1].Verses) {
-		fmt.Fprintf(w, "Verse %v.%v does not exist!\n", vars["chapter"], vars["verse"])
-		//TODO: redirect
+	verseNum, lastVerseNum := parseVerseRef(vars["verse"])
+
+	if chapterNum == 0 || chapterNum > len(book.Chapters) {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "chapter"), vars["chapter"]), verseSuggestions(vars["language"], book, chapterNum, verseNum))
+	} else if verseNum == 0 || verseNum > len(book.Chapters[chapterNum-1].Verses) {
+		notFound(w, r, fmt.Sprintf(errorText(vars["language"], "verse"), vars["chapter"], vars["verse"]), verseSuggestions(vars["language"], book, chapterNum, verseNum))
 	} else {
+		verses := book.Chapters[chapterNum-1].Group(verseNum)
+		verse := verses[len(verses)-1] // the last verse of a group carries Translation and Purport
+
+		// A verse inside a group or a partial range is shown on the page of the whole group: /lt/1/17 -> /lt/1/16-18
+		if verseNum != verse.From || lastVerseNum != verse.To {
+			url, err := verseURL(vars["language"], book, chapterNum, verseNum)
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, url.String(), 301)
+			return
+		}
+
 		fp := path.Join("templates", "verse.html")
-		tmpl, err := template.ParseFiles(fp)
+		tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			serverError(w, r, err)
 			return
 		}
 
-		verse := BG.Chapters[chapterNum-1].Verses[verseNum-1]
-
 		// fmt.Printf("Prev: %#v.%#v  Next: %#v.%#v \n", verse.PrevVerse[0], verse.PrevVerse[1], verse.NextVerse[0], verse.NextVerse[1])
 
 		var prevHref, nextHref, upHref template.HTML
 		if verse.PrevVerse[1] > 0 {
-			prevURL, urlErr := router.Get("langChapterVerse").URL("language", vars["language"], "chapter", strconv.Itoa(verse.PrevVerse[0]), "verse", strconv.Itoa(verse.PrevVerse[1]))
+			prevURL, urlErr := verseURL(vars["language"], book, verse.PrevVerse[0], verse.PrevVerse[1])
 			if urlErr != nil {
-				panic(urlErr)
+				serverError(w, r, urlErr)
+				return
+			}
+			prevHref = template.HTML(fmt.Sprintf(`<a href="%s">&lt;&lt;</a>`, prevURL.String()))
+		} else if langSections := bookSections(vars["language"]); len(langSections) > 0 {
+			// The very first verse is preceded by the front matter
+			prevURL, urlErr := router.Get("langSection").URL("language", vars["language"], "section", langSections[len(langSections)-1].Slug)
+			if urlErr != nil {
+				serverError(w, r, urlErr)
+				return
 			}
 			prevHref = template.HTML(fmt.Sprintf(`<a href="%s">&lt;&lt;</a>`, prevURL.String()))
 		} else {
@@ -212,46 +612,204 @@ func LangChapterVerseHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if verse.NextVerse[1] > 0 {
-			nextURL, urlErr := router.Get("langChapterVerse").URL("language", vars["language"], "chapter", strconv.Itoa(verse.NextVerse[0]), "verse", strconv.Itoa(verse.NextVerse[1]))
+			nextURL, urlErr := verseURL(vars["language"], book, verse.NextVerse[0], verse.NextVerse[1])
 			if urlErr != nil {
-				panic(urlErr)
+				serverError(w, r, urlErr)
+				return
 			}
 			nextHref = template.HTML(fmt.Sprintf(`<a href="%s">&gt;&gt;</a>`, nextURL.String()))
 		} else {
 			nextHref = `&gt;&gt;`
 		}
 
-		upURL, urlErr := router.Get("langChapter").URL("language", vars["language"], "chapter", strconv.Itoa(verse.NextVerse[0]))
+		upURL, urlErr := router.Get("langChapter").URL("language", vars["language"], "chapter", strconv.Itoa(chapterNum))
 		if urlErr != nil {
-			panic(urlErr)
+			serverError(w, r, urlErr)
+			return
 		}
 		upHref = template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String()))
 
-		// Join Synonyms string
+		// Join Synonyms string of all verses in a group
 		var synonyms template.HTML
 		var separator template.HTML = "; "
-		for i, v := range BG.Chapters[chapterNum-1].Verses[verseNum-1].SynonymsSanskrit {
-			if i == len(BG.Chapters[chapterNum-1].Verses[verseNum-1].SynonymsSanskrit)-1 { // last entry
-				separator = "."
+		for verseIdx, v := range verses {
+			for i, sanskrit := range v.SynonymsSanskrit {
+				if verseIdx == len(verses)-1 && i == len(v.SynonymsSanskrit)-1 { // last entry
+					separator = "."
+				}
+				sanskritHref := template.HTML(sanskrit)
+				if term := book.concordance.headword(normaliseTerm(sanskrit)); term != "" {
+					termURL, err := termURL(vars["language"], term)
+					if err != nil {
+						serverError(w, r, err)
+						return
+					}
+					sanskritHref = template.HTML(fmt.Sprintf(`<a href="%s"><i>%s</i></a>`, termURL.String(), template.HTMLEscapeString(sanskrit)))
+				}
+				synonyms += sanskritHref + "—" + v.SynonymsTranslation[i] + separator
+			}
+		}
+
+		// recitation chosen with ?recitation= or earlier, the first one with a recording of the verse by default;
+		// without a recording there is no player and the words are not timed
+		recitation := preferredRecitation(w, r, chapterNum, verse.From)
+		recorded := recitation.Has(chapterNum, verse.From)
+		// words of every verse of the group, numbered and timed through the group's recording
+		versesWords := func(track TimingTrack) [][][]Word {
+			if !recorded {
+				return timedGroupWords(verses, track, nil)
+			}
+			return recitation.GroupWords(chapterNum, verses, track)
+		}
+
+		// verse lines in the scripts chosen with ?script= or earlier, Devanagari and IAST as stored by default,
+		// with the word timings of the recitation
+		indic, roman := preferredSchemes(w, r)
+		devanagariTrack, _ := timingTrack("devanagari")
+		iastTrack, _ := timingTrack("iast")
+		var indicLines, romanLines [][]Word
+		for _, devanagariWords := range versesWords(devanagariTrack) {
+			if indic == translit.Devanagari {
+				indicLines = append(indicLines, devanagariWords...)
+			} else {
+				indicLines = append(indicLines, transliterateWords(devanagariWords, translit.FromDevanagari, indic)...)
+			}
+		}
+		for _, iastWords := range versesWords(iastTrack) {
+			if roman == translit.IAST {
+				romanLines = append(romanLines, iastWords...)
+			} else {
+				romanLines = append(romanLines, transliterateWords(iastWords, translit.FromIAST, roman)...)
+			}
+		}
+
+		// highlighting without recorded timings is only estimated
+		estimatedTimings := false
+		for _, lines := range [][][]Word{indicLines, romanLines} {
+			for _, line := range lines {
+				for _, word := range line {
+					estimatedTimings = estimatedTimings || word.Estimated
+				}
+			}
+		}
+
+		// caption tracks of the recitation, for the scripts with word timings
+		var captionsList []template.HTML
+		for _, track := range timingTracks {
+			var cues []Cue
+			for _, verseWords := range versesWords(track) {
+				cues = append(cues, captionCues(verseWords, "line")...)
+			}
+			if len(cues) == 0 {
+				continue
 			}
-			synonyms += template.HTML(v) + "—" + BG.Chapters[chapterNum-1].Verses[verseNum-1].SynonymsTranslation[i] + separator
+			captionsURL, urlErr := captionsURL(vars["language"], book, chapterNum, verseNum, "vtt", recitation.ID, track.ID)
+			if urlErr != nil {
+				serverError(w, r, urlErr)
+				return
+			}
+			captionsList = append(captionsList, template.HTML(fmt.Sprintf(`<track kind="captions" src="%s" srclang="sa" label="%s">`, template.HTMLEscapeString(captionsURL.String()), track.Label)))
+		}
+
+		var sourcesList []template.HTML
+		if recorded {
+			sourcesList = audioSources(recitation, chapterNum, verse)
+		}
+
+		// listen mode plays the verse and goes on to the next one with a recording until the end of the chapter
+		listening := r.URL.Query().Get("listen") != ""
+		var listenHref, listenNextHref string
+		if recorded {
+			listenURL, urlErr := listenURL(vars["language"], book, chapterNum, verse.From, recitation.ID)
+			if urlErr != nil {
+				serverError(w, r, urlErr)
+				return
+			}
+			listenHref = listenURL.String()
+		}
+		if next := nextRecorded(book.Chapters[chapterNum-1], verse, recitation); listening && next != nil {
+			listenNextURL, urlErr := listenURL(vars["language"], book, chapterNum, next.Num, recitation.ID)
+			if urlErr != nil {
+				serverError(w, r, urlErr)
+				return
+			}
+			listenNextHref = listenNextURL.String()
+			if !recorded {
+				// nothing to play here
+				http.Redirect(w, r, listenNextHref, http.StatusFound)
+				return
+			}
+		}
+
+		// construct reciter switcher, if there is a choice
+		var recitationsList []template.HTML
+		if verseRecitations := Recitations().ForVerse(chapterNum, verse.From); len(verseRecitations) > 1 {
+			for _, rec := range verseRecitations {
+				if rec == recitation {
+					recitationsList = append(recitationsList, template.HTML(fmt.Sprintf(`<b>%s</b>`, template.HTMLEscapeString(rec.Name(vars["language"])))))
+				} else {
+					recitationsList = append(recitationsList, template.HTML(fmt.Sprintf(`<a href="?recitation=%s">%s</a>`, url.QueryEscape(rec.ID), template.HTMLEscapeString(rec.Name(vars["language"])))))
+				}
+			}
+		}
+
+		// the verse group in every language, each corpus has the same chapters and verses
+		translation := strings.Join(strings.Fields(plainText(verse.Translation)), " ")
+		meta, err := newPageMeta(r, vars["language"], fmt.Sprintf("BG %d.%s – %s", chapterNum, verse.Ref(), excerpt(translation, 60)), excerpt(translation, 160),
+			func(languageID string) (*url.URL, error) {
+				return alternateVerseURL(languageID, chapterNum, verse.From)
+			})
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		meta.Type = "article"
+		chapterLD, err := chapterJSONLD(r, vars["language"], book.Chapters[chapterNum-1])
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		err = meta.setJSONLD(map[string]interface{}{
+			"@type":      "CreativeWork",
+			"name":       fmt.Sprintf("Bhagavad-gītā %d.%s", chapterNum, verse.Ref()),
+			"text":       translation,
+			"position":   verse.From,
+			"inLanguage": vars["language"],
+			"url":        meta.Canonical,
+			"isPartOf":   chapterLD,
+		})
+		if err != nil {
+			serverError(w, r, err)
+			return
 		}
 
 		data := map[string]interface{}{
-			"languageId": vars["language"],
-			"title":      pageTitle,
-			"keywords":   pageKeywords,
-			"chapterNum": chapterNum,
-			"verseNum":   verseNum,
-			"synonyms":   synonyms,
-			"verse":      BG.Chapters[chapterNum-1].Verses[verseNum-1],
-			"next":       nextHref,
-			"prev":       prevHref,
-			"up":         upHref,
+			"languageId":       vars["language"],
+			"meta":             meta,
+			"chapterNum":       chapterNum,
+			"verseNum":         verse.Ref(),
+			"audioSources":     sourcesList,
+			"listening":        listening,
+			"listenURL":        listenHref,
+			"listenNextURL":    listenNextHref,
+			"recitations":      recitationsList,
+			"captions":         captionsList,
+			"estimatedTimings": estimatedTimings,
+			"synonyms":         synonyms,
+			"verse":            verse,
+			"verses":           verses,
+			"devanagari":       indicLines,
+			"devanagariLang":   indic.Lang(),
+			"roman":            romanLines,
+			"indicScripts":     schemeSelector(translit.IndicSchemes, indic),
+			"romanScripts":     schemeSelector(translit.RomanSchemes, roman),
+			"next":             nextHref,
+			"prev":             prevHref,
+			"up":               upHref,
 		}
 
 		if err := tmpl.Execute(w, data); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			serverError(w, r, err)
 		}
 	}
 }
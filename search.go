@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+// searchResultsLimit - maximum number of results shown for a query
+var searchResultsLimit = 50
+
+// foldReplacer - folds diacritics of IAST and Lithuanian to plain Latin, so that queries typed without them match:
+// "Kṛṣṇa" -> "krisna", "sielą" -> "siela"
+var foldReplacer = strings.NewReplacer(
+	"ā", "a", "ī", "i", "ū", "u", "ṛ", "ri", "ṝ", "ri", "ḷ", "li",
+	"ṅ", "n", "ñ", "n", "ṭ", "t", "ḍ", "d", "ṇ", "n", "ś", "s", "ṣ", "s",
+	"ṁ", "m", "ḿ", "m", "ṃ", "m", "ḥ", "h",
+	"ą", "a", "č", "c", "ę", "e", "ė", "e", "į", "i", "š", "s", "ų", "u", "ž", "z",
+)
+
+// tagRe - HTML tags in translations and purports
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// lithuanianEndings - inflection endings stripped from query words, longest first, in folded form
+var lithuanianEndings = []string{"uose", "ams", "oms", "ims", "ose", "os", "as", "is", "es", "us", "ai", "ei", "ui", "a", "e", "i", "o", "u"}
+
+// foldWord - lowercase word without diacritics
+func foldWord(word string) string {
+	word = foldReplacer.Replace(strings.ToLower(word))
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) { // combining marks of decomposed letters
+			return -1
+		}
+		return r
+	}, word)
+}
+
+// stemWord - folded query word without its Lithuanian inflection ending: "sielos" -> "siel"
+func stemWord(word string) string {
+	if len(word) < 5 {
+		return word
+	}
+	for _, ending := range lithuanianEndings {
+		if strings.HasSuffix(word, ending) && len(word)-len(ending) >= 3 {
+			return word[:len(word)-len(ending)]
+		}
+	}
+	return word
+}
+
+// splitWords - words of a plain text; anything except letters and digits separates words
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+}
+
+// plainText - HTML fragment as plain text
+func plainText(fragment template.HTML) string {
+	return html.UnescapeString(tagRe.ReplaceAllString(string(fragment), " "))
+}
+
+// searchDoc - searchable text of a verse group
+type searchDoc struct {
+	Chapter  int
+	Verse    int      // first verse of the group
+	Ref      string   // "13" or "16-18"
+	Sections []string // plain text by weight: translation, synonyms, purport paragraphs
+}
+
+// searchIndex - inverted index of the folded words of translations, synonyms and purports
+type searchIndex struct {
+	languageID string // of the corpus, selects the inflection endings stripped from query words
+	docs       []searchDoc
+	terms      []string         // sorted, for prefix lookups
+	postings   map[string][]int // term -> sorted indexes in docs
+}
+
+// SearchResult - verse group matching a query
+type SearchResult struct {
+	Chapter int           `json:"chapter"`
+	Verse   int           `json:"verse"`
+	Ref     string        `json:"ref"`
+	URL     string        `json:"url"`
+	Snippet template.HTML `json:"snippet"`
+	score   int
+}
+
+// newSearchIndex - indexes every verse group of the book in a language
+func newSearchIndex(book *Book, languageID string) *searchIndex {
+	index := &searchIndex{languageID: languageID, postings: map[string][]int{}}
+	for _, chapter := range book.Chapters {
+		for verseIdx := 0; verseIdx < len(chapter.Verses); verseIdx = chapter.Verses[verseIdx].To {
+			verses := chapter.Group(verseIdx + 1)
+			carrier := verses[len(verses)-1]
+
+			var synonyms []string
+			for _, verse := range verses {
+				for _, translation := range verse.SynonymsTranslation {
+					synonyms = append(synonyms, plainText(translation))
+				}
+			}
+			doc := searchDoc{
+				Chapter:  chapter.Num,
+				Verse:    verses[0].Num,
+				Ref:      verses[0].Ref(),
+				Sections: []string{plainText(carrier.Translation), strings.Join(synonyms, "; ")},
+			}
+			for _, paragraph := range carrier.Purport {
+				doc.Sections = append(doc.Sections, plainText(paragraph))
+			}
+
+			docIdx := len(index.docs)
+			index.docs = append(index.docs, doc)
+			for _, section := range doc.Sections {
+				for _, word := range splitWords(section) {
+					term := foldWord(word)
+					if postings := index.postings[term]; len(postings) == 0 || postings[len(postings)-1] != docIdx {
+						index.postings[term] = append(postings, docIdx)
+					}
+				}
+			}
+		}
+	}
+
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+	return index
+}
+
+// queryTerms - folded words of a query, stemmed in Lithuanian
+func queryTerms(query, languageID string) []string {
+	var terms []string
+	for _, word := range splitWords(query) {
+		term := foldWord(word)
+		if languageID == "lt" {
+			term = stemWord(term)
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// matchesTerms - whether a word (or a part of a hyphenated or quoted word) starts with any of the query terms
+func matchesTerms(word string, terms []string) bool {
+	for _, part := range splitWords(word) {
+		folded := foldWord(part)
+		for _, term := range terms {
+			if strings.HasPrefix(folded, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Search - verse groups containing all words of the query, best matches first.
+// Query words match any word they are a prefix of, once Lithuanian endings are stripped in the Lithuanian corpus.
+func (index *searchIndex) Search(query string) []SearchResult {
+	terms := queryTerms(query, index.languageID)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var matching map[int]bool
+	for _, term := range terms {
+		termDocs := map[int]bool{}
+		for i := sort.SearchStrings(index.terms, term); i < len(index.terms) && strings.HasPrefix(index.terms[i], term); i++ {
+			for _, docIdx := range index.postings[index.terms[i]] {
+				if matching == nil || matching[docIdx] {
+					termDocs[docIdx] = true
+				}
+			}
+		}
+		matching = termDocs
+	}
+
+	var results []SearchResult
+	for docIdx := range matching {
+		doc := index.docs[docIdx]
+		result := SearchResult{Chapter: doc.Chapter, Verse: doc.Verse, Ref: doc.Ref}
+
+		// Translation counts more than synonyms, synonyms more than the purport
+		for sectionIdx, section := range doc.Sections {
+			weight := 1
+			if sectionIdx == 0 {
+				weight = 5
+			} else if sectionIdx == 1 {
+				weight = 2
+			}
+			for _, word := range strings.Fields(section) {
+				if matchesTerms(word, terms) {
+					if result.Snippet == "" {
+						result.Snippet = snippet(section, terms)
+					}
+					result.score += weight
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		if results[i].Chapter != results[j].Chapter {
+			return results[i].Chapter < results[j].Chapter
+		}
+		return results[i].Verse < results[j].Verse
+	})
+	return results
+}
+
+// snippet - words around the first match in text with the matching words marked
+func snippet(text string, terms []string) template.HTML {
+	const context = 12
+	words := strings.Fields(text)
+
+	first := 0
+	for i, word := range words {
+		if matchesTerms(word, terms) {
+			first = i
+			break
+		}
+	}
+	from, to := first-context, first+context
+	if from < 0 {
+		from = 0
+	}
+	if to > len(words) {
+		to = len(words)
+	}
+
+	var parts []string
+	if from > 0 {
+		parts = append(parts, "…")
+	}
+	for _, word := range words[from:to] {
+		if matchesTerms(word, terms) {
+			parts = append(parts, "<mark>"+template.HTMLEscapeString(word)+"</mark>")
+		} else {
+			parts = append(parts, template.HTMLEscapeString(word))
+		}
+	}
+	if to < len(words) {
+		parts = append(parts, "…")
+	}
+	return template.HTML(strings.Join(parts, " "))
+}
+
+// LangSearchHandler - search results page: /lt/paieska?q=siela, or JSON with &format=json
+func LangSearchHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	book := langBook(r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	results := book.search.Search(query)
+	total := len(results)
+	if len(results) > searchResultsLimit {
+		results = results[:searchResultsLimit]
+	}
+	for i := range results {
+		url, err := verseURL(vars["language"], book, results[i].Chapter, results[i].Verse)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		results[i].URL = url.String()
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		if results == nil {
+			results = []SearchResult{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"query":   query,
+			"total":   total,
+			"results": results,
+		})
+		return
+	}
+
+	fp := path.Join("templates", "search.html")
+	tmpl, err := template.ParseFiles(fp, path.Join("templates", "head.html"))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	upURL, urlErr := router.Get("langIndex").URL("language", vars["language"])
+	if urlErr != nil {
+		serverError(w, r, urlErr)
+		return
+	}
+	upHref := template.HTML(fmt.Sprintf(`<a href="%s">^</a>`, upURL.String()))
+
+	// result pages are not indexed, the search page itself is
+	title := pageName(vars["language"], "search") + " – " + localised(bookTitles, vars["language"])
+	if query != "" {
+		title = pageName(vars["language"], "search") + ": " + query + " – " + localised(bookTitles, vars["language"])
+	}
+	meta, err := newPageMeta(r, vars["language"], title, localised(bookDescriptions, vars["language"]), func(languageID string) (*url.URL, error) {
+		return router.Get("langSearch").URL("language", languageID, "search", pageSlug(languageID, "search"))
+	})
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if query != "" {
+		meta.Robots = "noindex, follow"
+	}
+
+	data := map[string]interface{}{
+		"languageId": vars["language"],
+		"meta":       meta,
+		"query":      query,
+		"total":      total,
+		"results":    results,
+		"up":         upHref,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		serverError(w, r, err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// bookAuthor - author of the book for the structured data
+const bookAuthor = "A.C. Bhaktivedanta Swami Prabhupāda"
+
+// bookTitles - title of the book by language, the site name of the pages
+var bookTitles = map[string]string{
+	"lt": "Bhagavad-gīta, kokia ji yra",
+	"en": "Bhagavad-Gita As It Is",
+}
+
+// bookDescriptions - description of the book by language, for the pages without one of their own
+var bookDescriptions = map[string]string{
+	"lt": "Bhagavad-gīta, kokia ji yra. A.C. Bhaktivedantos Swami Prabhupādos vertimas ir komentarai",
+	"en": "Bhagavad-Gita As It Is. Translation and commentaries by HDG A.C.Bhaktivedanta Swami Prabhupada",
+}
+
+// keywords - meta keywords by language
+var keywords = map[string]string{
+	"lt": "Bhagavad-gīta, kokia ji yra, Bhagavad-gītā, Bhagavad Gita, gita, Krišna, ISKCON, Prabhupada, A.C. Bhaktivedanta Swami",
+	"en": "Bhagavad Gita As It Is, Bhagavad-gītā, Bhagavad Gita, gita, ISKCON, Prabhupada, A.C. Bhaktivedanta Swami",
+}
+
+// ogLocales - OpenGraph locales of the languages
+var ogLocales = map[string]string{
+	"lt": "lt_LT",
+	"en": "en_US",
+}
+
+// pageNames - localised names of other pages by language, like pageSlugs
+var pageNames = map[string]map[string]string{
+	"lt": {"search": "Paieška", "glossary": "Sanskrito žodynas"},
+	"en": {"search": "Search", "glossary": "Sanskrit glossary"},
+}
+
+// localised - the value for the language, English one if the language has no own
+func localised(values map[string]string, languageID string) string {
+	if value, ok := values[languageID]; ok {
+		return value
+	}
+	return values["en"]
+}
+
+// pageName - name of a page in a language, English one if the language has no own
+func pageName(languageID, page string) string {
+	if name, ok := pageNames[languageID][page]; ok {
+		return name
+	}
+	return pageNames["en"][page]
+}
+
+// Alternate - the same page in another language
+type Alternate struct {
+	Lang string // hreflang: "lt", "x-default"
+	URL  string
+}
+
+// PageMeta - <head> metadata of a page, rendered by the "head" template
+type PageMeta struct {
+	Title       string
+	Description string
+	Keywords    string
+	Robots      string // "" - index the page
+	Type        string // og:type: "website", "book", "article"
+	Locale      string
+	SiteName    string
+	Canonical   string // absolute URL of the page without parameters
+	Alternates  []Alternate
+	JSONLD      template.JS // schema.org structured data
+}
+
+// newPageMeta - metadata of the page in the language with the canonical URL and the hreflang alternates
+// built by pageURL for every loaded language; pageURL returns nil for a language without the page
+func newPageMeta(r *http.Request, languageID, title, description string, pageURL func(languageID string) (*url.URL, error)) (PageMeta, error) {
+	meta := PageMeta{
+		Title:       title,
+		Description: description,
+		Keywords:    localised(keywords, languageID),
+		Type:        "website",
+		Locale:      localised(ogLocales, languageID),
+		SiteName:    localised(bookTitles, languageID),
+	}
+	for _, lang := range languages() {
+		langURL, err := pageURL(lang)
+		if err != nil {
+			return meta, err
+		}
+		if langURL == nil {
+			continue
+		}
+		if lang == languageID {
+			meta.Canonical = absoluteURL(r, langURL)
+		}
+		meta.Alternates = append(meta.Alternates, Alternate{Lang: lang, URL: absoluteURL(r, langURL)})
+		if lang == defaultLangID {
+			meta.Alternates = append(meta.Alternates, Alternate{Lang: "x-default", URL: absoluteURL(r, langURL)})
+		}
+	}
+	return meta, nil
+}
+
+// setJSONLD - sets the schema.org structured data; json.Marshal escapes <, > and & for the <script> element
+func (meta *PageMeta) setJSONLD(data map[string]interface{}) error {
+	data["@context"] = "https://schema.org"
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	meta.JSONLD = template.JS(encoded)
+	return nil
+}
+
+// excerpt - plain text shortened to at most max characters at a word boundary
+func excerpt(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	cut := string([]rune(text)[:max-1])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:—–-") + "…"
+}
+
+// bookJSONLD - schema.org Book of the language
+func bookJSONLD(r *http.Request, languageID string) (map[string]interface{}, error) {
+	indexURL, err := router.Get("langIndex").URL("language", languageID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"@type":      "Book",
+		"name":       localised(bookTitles, languageID),
+		"author":     map[string]interface{}{"@type": "Person", "name": bookAuthor},
+		"inLanguage": languageID,
+		"url":        absoluteURL(r, indexURL),
+	}, nil
+}
+
+// chapterJSONLD - schema.org Chapter, part of the Book
+func chapterJSONLD(r *http.Request, languageID string, chapter Chapter) (map[string]interface{}, error) {
+	chapterURL, err := router.Get("langChapter").URL("language", languageID, "chapter", strconv.Itoa(chapter.Num))
+	if err != nil {
+		return nil, err
+	}
+	book, err := bookJSONLD(r, languageID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"@type":      "Chapter",
+		"name":       fmt.Sprintf("%d. %s", chapter.Num, chapter.Name),
+		"position":   chapter.Num,
+		"inLanguage": languageID,
+		"url":        absoluteURL(r, chapterURL),
+		"isPartOf":   book,
+	}, nil
+}
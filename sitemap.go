@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// robotsFile - robots.txt served with the Sitemap line added
+const robotsFile = "robots.txt"
+
+// sitemapLimit - URLs of a single sitemap; above it /sitemap.xml is an index of the sitemaps of the languages
+const sitemapLimit = 50000
+
+// sitemapURLSet - sitemap with the hreflang alternates of the pages
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	XHTML   string       `xml:"xmlns:xhtml,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapURL - page of a sitemap
+type sitemapURL struct {
+	Loc        string             `xml:"loc"`
+	LastMod    string             `xml:"lastmod,omitempty"`
+	Alternates []sitemapAlternate `xml:"xhtml:link"`
+}
+
+// sitemapAlternate - the page in another language
+type sitemapAlternate struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// sitemapIndex - index of the sitemaps of the languages
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry - sitemap of a language in the index
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapURLs - pages of a language: the contents, front matter, search, glossary, chapters, verse groups and terms,
+// last modified with the corpus
+func sitemapURLs(r *http.Request, languageID string) ([]sitemapURL, error) {
+	book := Corpora()[languageID]
+	var lastMod string
+	if !book.modTime.IsZero() {
+		lastMod = book.modTime.UTC().Format(time.RFC3339)
+	}
+
+	var urls []sitemapURL
+	var err error // the first URL build error, the pages after it are skipped
+	add := func(pageURL func(languageID string) (*url.URL, error)) {
+		if err != nil {
+			return
+		}
+		var meta PageMeta
+		if meta, err = newPageMeta(r, languageID, "", "", pageURL); err != nil || meta.Canonical == "" {
+			return
+		}
+		page := sitemapURL{Loc: meta.Canonical, LastMod: lastMod}
+		for _, alternate := range meta.Alternates {
+			page.Alternates = append(page.Alternates, sitemapAlternate{Rel: "alternate", Hreflang: alternate.Lang, Href: alternate.URL})
+		}
+		urls = append(urls, page)
+	}
+
+	add(func(languageID string) (*url.URL, error) {
+		return router.Get("langIndex").URL("language", languageID)
+	})
+	for _, section := range bookSections(languageID) {
+		add(sectionURLs(section.Key))
+	}
+	add(func(languageID string) (*url.URL, error) {
+		return router.Get("langSearch").URL("language", languageID, "search", pageSlug(languageID, "search"))
+	})
+	add(func(languageID string) (*url.URL, error) {
+		return router.Get("langGlossary").URL("language", languageID, "glossary", pageSlug(languageID, "glossary"))
+	})
+	for _, chapter := range book.Chapters {
+		chapterNum := chapter.Num
+		add(func(languageID string) (*url.URL, error) {
+			return alternateChapterURL(languageID, chapterNum)
+		})
+		for verseIdx := 0; verseIdx < len(chapter.Verses); verseIdx = chapter.Verses[verseIdx].To {
+			verseNum := chapter.Verses[verseIdx].Num
+			add(func(languageID string) (*url.URL, error) {
+				return alternateVerseURL(languageID, chapterNum, verseNum)
+			})
+		}
+	}
+	for _, term := range book.concordance.terms {
+		add(termURLs(term))
+	}
+	return urls, err
+}
+
+// writeSitemapXML - writes an XML sitemap document
+func writeSitemapXML(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+	w.Write([]byte("\n"))
+}
+
+// SitemapHandler - sitemap of all languages, or the index of their sitemaps if it would be too long: /sitemap.xml
+func SitemapHandler(w http.ResponseWriter, r *http.Request) {
+	urlSet := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", XHTML: "http://www.w3.org/1999/xhtml"}
+	for _, languageID := range languages() {
+		urls, err := sitemapURLs(r, languageID)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		urlSet.URLs = append(urlSet.URLs, urls...)
+	}
+	if len(urlSet.URLs) <= sitemapLimit {
+		writeSitemapXML(w, r, urlSet)
+		return
+	}
+
+	index := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, languageID := range languages() {
+		langSitemapURL, err := router.Get("langSitemap").URL("language", languageID)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		entry := sitemapEntry{Loc: absoluteURL(r, langSitemapURL)}
+		if modTime := Corpora()[languageID].modTime; !modTime.IsZero() {
+			entry.LastMod = modTime.UTC().Format(time.RFC3339)
+		}
+		index.Sitemaps = append(index.Sitemaps, entry)
+	}
+	writeSitemapXML(w, r, index)
+}
+
+// LangSitemapHandler - sitemap of a language: /lt/sitemap.xml
+func LangSitemapHandler(w http.ResponseWriter, r *http.Request) {
+	urls, err := sitemapURLs(r, mux.Vars(r)["language"])
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	urlSet := sitemapURLSet{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		XHTML: "http://www.w3.org/1999/xhtml",
+		URLs:  urls,
+	}
+	writeSitemapXML(w, r, urlSet)
+}
+
+// RobotsHandler - robots.txt of the repository root with the URL of the sitemap: /robots.txt
+func RobotsHandler(w http.ResponseWriter, r *http.Request) {
+	robots, err := ioutil.ReadFile(robotsFile)
+	if err != nil && !os.IsNotExist(err) {
+		serverError(w, r, err)
+		return
+	}
+	sitemapURL, err := router.Get("sitemap").URL()
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	var out bytes.Buffer
+	out.Write(bytes.TrimSpace(robots))
+	if out.Len() > 0 {
+		out.WriteString("\n\n")
+	}
+	out.WriteString("Sitemap: " + absoluteURL(r, sitemapURL) + "\n")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(out.Bytes())
+}
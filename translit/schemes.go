@@ -0,0 +1,178 @@
+package translit
+
+// Devanagari signs outside of the letter tables
+const (
+	virama      = '्'
+	nukta       = '़'
+	danda       = '।'
+	doubleDanda = '॥'
+	zwnj        = '\u200c'
+	zwj         = '\u200d'
+)
+
+// Devanagari letters by their IAST phonemes
+var (
+	devaConsonants = map[rune]string{
+		'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "ṅ",
+		'च': "c", 'छ': "ch", 'ज': "j", 'झ': "jh", 'ञ': "ñ",
+		'ट': "ṭ", 'ठ': "ṭh", 'ड': "ḍ", 'ढ': "ḍh", 'ण': "ṇ",
+		'त': "t", 'थ': "th", 'द': "d", 'ध': "dh", 'न': "n",
+		'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh", 'म': "m",
+		'य': "y", 'र': "r", 'ल': "l", 'व': "v",
+		'श': "ś", 'ष': "ṣ", 'स': "s", 'ह': "h",
+	}
+	devaVowels = map[rune]string{
+		'अ': "a", 'आ': "ā", 'इ': "i", 'ई': "ī", 'उ': "u", 'ऊ': "ū",
+		'ऋ': "ṛ", 'ॠ': "ṝ", 'ऌ': "ḷ", 'ॡ': "ḹ",
+		'ए': "e", 'ऐ': "ai", 'ओ': "o", 'औ': "au",
+	}
+	devaVowelSigns = map[rune]string{
+		'ा': "ā", 'ि': "i", 'ी': "ī", 'ु': "u", 'ू': "ū",
+		'ृ': "ṛ", 'ॄ': "ṝ", 'ॢ': "ḷ", 'ॣ': "ḹ",
+		'े': "e", 'ै': "ai", 'ो': "o", 'ौ': "au",
+	}
+	devaModifiers = map[rune]string{
+		'ं': "ṁ", 'ः': "ḥ", 'ँ': "m̐",
+	}
+	devaOthers = map[rune]string{
+		'ऽ': "'", '।': "|", '॥': "||", 'ॐ': "oṁ",
+		'०': "0", '१': "1", '२': "2", '३': "3", '४': "4", '५': "5", '६': "6", '७': "7", '८': "8", '९': "9",
+	}
+)
+
+// Unicode blocks of the Brahmic scripts, laid out in parallel to Devanagari
+const (
+	devaBlock = '\u0900'
+	blockSize = 0x80
+)
+
+// brahmicBlocks - first code point of the Unicode block of a Brahmic script
+var brahmicBlocks = map[Scheme]rune{
+	Bengali:   '\u0980',
+	Gujarati:  '\u0a80',
+	Oriya:     '\u0b00',
+	Telugu:    '\u0c00',
+	Kannada:   '\u0c80',
+	Malayalam: '\u0d00',
+}
+
+// brahmicExceptions - Devanagari letters which have no counterpart at the same offset of a script's block
+var brahmicExceptions = map[Scheme]map[rune]string{
+	Bengali:   {'व': "ব", 'ॐ': "ওঁ"}, // Bengali writes va as ba
+	Oriya:     {'ॐ': "ଓଁ"},
+	Telugu:    {'ॐ': "ఓం"},
+	Kannada:   {'ॐ': "ಓಂ"},
+	Malayalam: {'ॐ': "ഓം"},
+}
+
+// brahmicFinals - word final forms of dead consonants: Bengali khanda ta, Malayalam chillus
+var brahmicFinals = map[Scheme]map[rune]rune{
+	Bengali:   {'त': 'ৎ'},
+	Malayalam: {'ण': 'ൺ', 'न': 'ൻ', 'र': 'ർ', 'ल': 'ൽ', 'ळ': 'ൾ', 'क': 'ൿ'},
+}
+
+// Reverse tables: Devanagari letters by IAST phonemes, filled in init
+var (
+	devaConsonantLetters = map[string]rune{}
+	devaVowelLetters     = map[string]rune{}
+	devaVowelSignLetters = map[string]rune{}
+	devaModifierLetters  = map[string]rune{}
+	devaOtherLetters     = map[string]string{}
+)
+
+// iastPhonemes - IAST letters, including the BBT spellings ḿ, ń and the dotted ṃ, by their tokens
+var iastPhonemes = map[string]Token{
+	"'": {Other, "'"},
+	"ḿ": {Modifier, "ṁ"}, "ṃ": {Modifier, "ṁ"}, "ń": {Consonant, "ṅ"},
+}
+
+// iastCompositions - letter and combining diacritic -> precomposed letter
+var iastCompositions = map[[2]rune]rune{
+	{'a', '\u0304'}: 'ā', {'i', '\u0304'}: 'ī', {'u', '\u0304'}: 'ū', {'ṛ', '\u0304'}: 'ṝ', {'ḷ', '\u0304'}: 'ḹ',
+	{'r', '\u0323'}: 'ṛ', {'l', '\u0323'}: 'ḷ', {'t', '\u0323'}: 'ṭ', {'d', '\u0323'}: 'ḍ', {'n', '\u0323'}: 'ṇ',
+	{'s', '\u0323'}: 'ṣ', {'h', '\u0323'}: 'ḥ', {'m', '\u0323'}: 'ṃ',
+	{'r', '\u0325'}: 'ṛ', {'l', '\u0325'}: 'ḷ',
+	{'s', '\u0301'}: 'ś', {'m', '\u0301'}: 'ḿ', {'n', '\u0301'}: 'ń',
+	{'n', '\u0307'}: 'ṅ', {'m', '\u0307'}: 'ṁ',
+	{'n', '\u0303'}: 'ñ',
+	{'i', '\u0308'}: 'ï', {'u', '\u0308'}: 'ü',
+}
+
+// romanTables - Roman spelling of every IAST phoneme by scheme
+var romanTables = map[Scheme]map[string]string{
+	IAST: {
+		"a": "a", "ā": "ā", "i": "i", "ī": "ī", "u": "u", "ū": "ū", "ṛ": "ṛ", "ṝ": "ṝ", "ḷ": "ḷ", "ḹ": "ḹ",
+		"e": "e", "ai": "ai", "o": "o", "au": "au", "ṁ": "ṁ", "ḥ": "ḥ", "m̐": "m̐",
+		"k": "k", "kh": "kh", "g": "g", "gh": "gh", "ṅ": "ṅ", "c": "c", "ch": "ch", "j": "j", "jh": "jh", "ñ": "ñ",
+		"ṭ": "ṭ", "ṭh": "ṭh", "ḍ": "ḍ", "ḍh": "ḍh", "ṇ": "ṇ", "t": "t", "th": "th", "d": "d", "dh": "dh", "n": "n",
+		"p": "p", "ph": "ph", "b": "b", "bh": "bh", "m": "m", "y": "y", "r": "r", "l": "l", "v": "v",
+		"ś": "ś", "ṣ": "ṣ", "s": "s", "h": "h",
+	},
+	HarvardKyoto: {
+		"a": "a", "ā": "A", "i": "i", "ī": "I", "u": "u", "ū": "U", "ṛ": "R", "ṝ": "RR", "ḷ": "lR", "ḹ": "lRR",
+		"e": "e", "ai": "ai", "o": "o", "au": "au", "ṁ": "M", "ḥ": "H", "m̐": "~",
+		"k": "k", "kh": "kh", "g": "g", "gh": "gh", "ṅ": "G", "c": "c", "ch": "ch", "j": "j", "jh": "jh", "ñ": "J",
+		"ṭ": "T", "ṭh": "Th", "ḍ": "D", "ḍh": "Dh", "ṇ": "N", "t": "t", "th": "th", "d": "d", "dh": "dh", "n": "n",
+		"p": "p", "ph": "ph", "b": "b", "bh": "bh", "m": "m", "y": "y", "r": "r", "l": "l", "v": "v",
+		"ś": "z", "ṣ": "S", "s": "s", "h": "h",
+	},
+	ITRANS: {
+		"a": "a", "ā": "A", "i": "i", "ī": "I", "u": "u", "ū": "U", "ṛ": "RRi", "ṝ": "RRI", "ḷ": "LLi", "ḹ": "LLI",
+		"e": "e", "ai": "ai", "o": "o", "au": "au", "ṁ": "M", "ḥ": "H", "m̐": ".N",
+		"k": "k", "kh": "kh", "g": "g", "gh": "gh", "ṅ": "~N", "c": "ch", "ch": "Ch", "j": "j", "jh": "jh", "ñ": "~n",
+		"ṭ": "T", "ṭh": "Th", "ḍ": "D", "ḍh": "Dh", "ṇ": "N", "t": "t", "th": "th", "d": "d", "dh": "dh", "n": "n",
+		"p": "p", "ph": "ph", "b": "b", "bh": "bh", "m": "m", "y": "y", "r": "r", "l": "l", "v": "v",
+		"ś": "sh", "ṣ": "Sh", "s": "s", "h": "h",
+	},
+	Velthuis: {
+		"a": "a", "ā": "aa", "i": "i", "ī": "ii", "u": "u", "ū": "uu", "ṛ": ".r", "ṝ": ".rr", "ḷ": ".l", "ḹ": ".ll",
+		"e": "e", "ai": "ai", "o": "o", "au": "au", "ṁ": ".m", "ḥ": ".h", "m̐": "~m",
+		"k": "k", "kh": "kh", "g": "g", "gh": "gh", "ṅ": "\"n", "c": "c", "ch": "ch", "j": "j", "jh": "jh", "ñ": "~n",
+		"ṭ": ".t", "ṭh": ".th", "ḍ": ".d", "ḍh": ".dh", "ṇ": ".n", "t": "t", "th": "th", "d": "d", "dh": "dh", "n": "n",
+		"p": "p", "ph": "ph", "b": "b", "bh": "bh", "m": "m", "y": "y", "r": "r", "l": "l", "v": "v",
+		"ś": "\"s", "ṣ": ".s", "s": "s", "h": "h",
+	},
+	Simple: {
+		"a": "a", "ā": "a", "i": "i", "ī": "i", "u": "u", "ū": "u", "ṛ": "ri", "ṝ": "ri", "ḷ": "li", "ḹ": "li",
+		"e": "e", "ai": "ai", "o": "o", "au": "au", "ṁ": "m", "ḥ": "h", "m̐": "m",
+		"k": "k", "kh": "kh", "g": "g", "gh": "gh", "ṅ": "n", "c": "č", "ch": "čh", "j": "dž", "jh": "džh", "ñ": "n",
+		"ṭ": "t", "ṭh": "th", "ḍ": "d", "ḍh": "dh", "ṇ": "n", "t": "t", "th": "th", "d": "d", "dh": "dh", "n": "n",
+		"p": "p", "ph": "ph", "b": "b", "bh": "bh", "m": "m", "y": "j", "r": "r", "l": "l", "v": "v",
+		"ś": "š", "ṣ": "š", "s": "s", "h": "h",
+	},
+}
+
+// avagrahas - spelling of the avagraha ऽ by scheme
+var avagrahas = map[Scheme]string{
+	IAST: "'", HarvardKyoto: "'", ITRANS: ".a", Velthuis: ".a", Simple: "'",
+}
+
+// hiatusMarks - spelling of i and u directly after a, so that they are not read as the diphthongs ai, au
+var hiatusMarks = map[Scheme]map[string]string{
+	IAST:         {"i": "ï", "u": "ü"},
+	HarvardKyoto: {"i": "i", "u": "u"},
+	ITRANS:       {"i": "_i", "u": "_u"},
+	Velthuis:     {"i": "{}i", "u": "{}u"},
+	Simple:       {"i": "ï", "u": "ü"},
+}
+
+func init() {
+	for letter, phoneme := range devaConsonants {
+		devaConsonantLetters[phoneme] = letter
+		iastPhonemes[phoneme] = Token{Consonant, phoneme}
+	}
+	for letter, phoneme := range devaVowels {
+		devaVowelLetters[phoneme] = letter
+		iastPhonemes[phoneme] = Token{Vowel, phoneme}
+	}
+	for letter, phoneme := range devaVowelSigns {
+		devaVowelSignLetters[phoneme] = letter
+	}
+	for letter, phoneme := range devaModifiers {
+		devaModifierLetters[phoneme] = letter
+		iastPhonemes[phoneme] = Token{Modifier, phoneme}
+	}
+	for letter, text := range devaOthers {
+		devaOtherLetters[text] = string(letter)
+	}
+}
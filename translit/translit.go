@@ -0,0 +1,336 @@
+// Package translit converts Sanskrit text between Devanagari, other Brahmic scripts and
+// Roman transliteration schemes.
+//
+// Text is parsed into tokens - IAST phonemes and any other characters - which are then
+// rendered in the requested scheme:
+//
+//	translit.FromDevanagari("कृष्ण", translit.IAST)   // "kṛṣṇa"
+//	translit.FromIAST("kṛṣṇa", translit.HarvardKyoto) // "kRSNa"
+//	translit.FromIAST("kṛṣṇa", translit.Devanagari)   // "कृष्ण"
+//	translit.FromDevanagari("कृष्ण", translit.Bengali) // "কৃষ্ণ"
+package translit
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Kind - kind of a token
+type Kind int
+
+const (
+	// Other - anything that is not a Sanskrit sound: spaces, hyphens, punctuation, dandas, digits, avagraha
+	Other Kind = iota
+	// Vowel - a, ā, i ... au; the inherent vowel of a Devanagari consonant is a Vowel "a" of its own
+	Vowel
+	// Consonant - k, kh ... h
+	Consonant
+	// Modifier - anusvāra ṁ, visarga ḥ or candrabindu m̐ following a vowel
+	Modifier
+)
+
+// Token - an IAST phoneme (ṁ for anusvāra) or other text (' for avagraha, | and || for dandas, oṁ for ॐ)
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// IsLetter - whether the token is a Sanskrit sound
+func (t Token) IsLetter() bool {
+	return t.Kind != Other
+}
+
+// Scheme - script or Roman transliteration scheme
+type Scheme string
+
+// Supported schemes
+const (
+	Devanagari   Scheme = "deva"
+	IAST         Scheme = "iast"
+	HarvardKyoto Scheme = "hk"
+	ITRANS       Scheme = "itrans"
+	Velthuis     Scheme = "velthuis"
+	Simple       Scheme = "simple" // Lithuanian-friendly spelling: Krišna, Bhagavad-gita
+	Bengali      Scheme = "beng"
+	Gujarati     Scheme = "gujr"
+	Oriya        Scheme = "orya"
+	Telugu       Scheme = "telu"
+	Kannada      Scheme = "knda"
+	Malayalam    Scheme = "mlym"
+)
+
+// RomanSchemes - Roman schemes in display order
+var RomanSchemes = []Scheme{IAST, HarvardKyoto, ITRANS, Velthuis, Simple}
+
+// IndicSchemes - Brahmic scripts in display order, Devanagari first
+var IndicSchemes = []Scheme{Devanagari, Bengali, Gujarati, Oriya, Telugu, Kannada, Malayalam}
+
+// schemeNames - display names of the schemes
+var schemeNames = map[Scheme]string{
+	Devanagari:   "देवनागरी",
+	IAST:         "IAST",
+	HarvardKyoto: "Harvard-Kyoto",
+	ITRANS:       "ITRANS",
+	Velthuis:     "Velthuis",
+	Simple:       "Supaprastinta",
+	Bengali:      "বাংলা",
+	Gujarati:     "ગુજરાતી",
+	Oriya:        "ଓଡ଼ିଆ",
+	Telugu:       "తెలుగు",
+	Kannada:      "ಕನ್ನಡ",
+	Malayalam:    "മലയാളം",
+}
+
+// Name - display name of the scheme
+func (s Scheme) Name() string {
+	return schemeNames[s]
+}
+
+// IsRoman - whether the scheme is a Roman transliteration
+func (s Scheme) IsRoman() bool {
+	_, ok := romanTables[s]
+	return ok
+}
+
+// Lang - BCP 47 tag of Sanskrit written in the scheme: "sa-Beng"; all Roman schemes are "sa-Latn"
+func (s Scheme) Lang() string {
+	if s.IsRoman() {
+		return "sa-Latn"
+	}
+	return "sa-" + strings.ToUpper(string(s[:1])) + string(s[1:])
+}
+
+// ParseScheme - scheme by its ID, false for an unknown one
+func ParseScheme(id string) (Scheme, bool) {
+	scheme := Scheme(strings.ToLower(id))
+	_, ok := schemeNames[scheme]
+	return scheme, ok
+}
+
+// FromDevanagari - converts Devanagari text to the scheme
+func FromDevanagari(text string, to Scheme) string {
+	return Render(ParseDevanagari(text), to)
+}
+
+// FromIAST - converts IAST text to the scheme
+func FromIAST(text string, to Scheme) string {
+	return Render(ParseIAST(text), to)
+}
+
+// ParseDevanagari - splits Devanagari text into tokens. A consonant without a virama or a vowel sign
+// is followed by the inherent vowel "a". Zero width (non-)joiners and nuktas are dropped.
+func ParseDevanagari(text string) []Token {
+	runes := []rune(text)
+	next := func(i int) rune {
+		if i+1 < len(runes) {
+			return runes[i+1]
+		}
+		return 0
+	}
+
+	var tokens []Token
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if consonant, ok := devaConsonants[r]; ok {
+			tokens = append(tokens, Token{Consonant, consonant})
+			if next(i) == nukta {
+				i++
+			}
+			if next(i) == virama {
+				i++
+			} else if vowel, ok := devaVowelSigns[next(i)]; ok {
+				tokens = append(tokens, Token{Vowel, vowel})
+				i++
+			} else {
+				tokens = append(tokens, Token{Vowel, "a"})
+			}
+		} else if vowel, ok := devaVowels[r]; ok {
+			tokens = append(tokens, Token{Vowel, vowel})
+		} else if modifier, ok := devaModifiers[r]; ok {
+			tokens = append(tokens, Token{Modifier, modifier})
+		} else if r == zwj || r == zwnj || r == nukta {
+			continue
+		} else if r == danda && next(i) == danda {
+			tokens = append(tokens, Token{Other, "||"})
+			i++
+		} else if other, ok := devaOthers[r]; ok {
+			tokens = append(tokens, Token{Other, other})
+		} else {
+			tokens = append(tokens, Token{Other, string(r)})
+		}
+	}
+	return tokens
+}
+
+// ParseIAST - splits IAST text into tokens. Upper case is folded, decomposed diacritics are composed
+// and the BBT spellings ḿ, ń, ṃ are read as ṁ, ṅ, ṁ; "aï", "aü" mark a hiatus.
+func ParseIAST(text string) []Token {
+	runes := []rune(composeIAST(strings.ToLower(text)))
+
+	var tokens []Token
+	for i := 0; i < len(runes); {
+		// longest match: two-letter phonemes like "kh", "ai" and "m̐" first
+		if i+1 < len(runes) {
+			if token, ok := iastPhonemes[string(runes[i:i+2])]; ok {
+				tokens = append(tokens, token)
+				i += 2
+				continue
+			}
+		}
+		r := runes[i]
+		if token, ok := iastPhonemes[string(r)]; ok {
+			tokens = append(tokens, token)
+		} else if r == '|' && i+1 < len(runes) && runes[i+1] == '|' {
+			tokens = append(tokens, Token{Other, "||"})
+			i++
+		} else if r == 'ï' || r == 'ü' { // hiatus: "aïkṣata"
+			tokens = append(tokens, Token{Vowel, map[rune]string{'ï': "i", 'ü': "u"}[r]})
+		} else if r == '’' {
+			tokens = append(tokens, Token{Other, "'"})
+		} else {
+			tokens = append(tokens, Token{Other, string(r)})
+		}
+		i++
+	}
+	return tokens
+}
+
+// composeIAST - replaces letters followed by combining diacritics with precomposed ones: "ṛ" -> "ṛ"
+func composeIAST(text string) string {
+	runes := []rune(text)
+	var composed []rune
+	for _, r := range runes {
+		if n := len(composed); n > 0 {
+			if precomposed, ok := iastCompositions[[2]rune{composed[n-1], r}]; ok {
+				composed[n-1] = precomposed
+				continue
+			}
+		}
+		composed = append(composed, r)
+	}
+	return string(composed)
+}
+
+// nextLetter - index of the letter following tokens[i], skipping a hyphen between letters of a compound; -1 if none
+func nextLetter(tokens []Token, i int) int {
+	if i+1 < len(tokens) && tokens[i+1].IsLetter() {
+		return i + 1
+	}
+	if i+2 < len(tokens) && tokens[i+1].Text == "-" && tokens[i+2].IsLetter() {
+		return i + 2
+	}
+	return -1
+}
+
+// Render - writes tokens in the scheme. Brahmic scripts join compounds written with hyphens in IAST.
+func Render(tokens []Token, to Scheme) string {
+	if to == Devanagari {
+		return renderDevanagari(tokens)
+	}
+	if _, ok := brahmicBlocks[to]; ok {
+		return renderBrahmic(renderDevanagari(tokens), to)
+	}
+	table, ok := romanTables[to]
+	if !ok {
+		table = romanTables[IAST]
+	}
+
+	var out bytes.Buffer
+	for i, token := range tokens {
+		if !token.IsLetter() {
+			if token.Text == "'" {
+				out.WriteString(avagrahas[to])
+			} else if token.Text == "oṁ" {
+				out.WriteString(table["o"] + table["ṁ"])
+			} else {
+				out.WriteString(token.Text)
+			}
+			continue
+		}
+		// a hiatus has to be marked not to be read as a diphthong: a + i is not ai
+		if token.Kind == Vowel && (token.Text == "i" || token.Text == "u") && i > 0 && tokens[i-1] == (Token{Vowel, "a"}) {
+			out.WriteString(hiatusMarks[to][token.Text])
+			continue
+		}
+		out.WriteString(table[token.Text])
+	}
+	return out.String()
+}
+
+// renderDevanagari - consonants take the following vowel as a vowel sign or a virama if no vowel follows
+func renderDevanagari(tokens []Token) string {
+	var out bytes.Buffer
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token.Kind {
+		case Consonant:
+			out.WriteRune(devaConsonantLetters[token.Text])
+			next := nextLetter(tokens, i)
+			if next >= 0 && tokens[next].Kind == Vowel {
+				if token := tokens[next].Text; token != "a" {
+					out.WriteRune(devaVowelSignLetters[token])
+				}
+				i = next
+			} else {
+				out.WriteRune(virama)
+				if next >= 0 {
+					i = next - 1 // skip the hyphen of a compound
+				}
+			}
+		case Vowel:
+			out.WriteRune(devaVowelLetters[token.Text])
+		case Modifier:
+			out.WriteRune(devaModifierLetters[token.Text])
+		default:
+			if letter, ok := devaOtherLetters[token.Text]; ok {
+				out.WriteString(letter)
+			} else if token.Text == "-" && i > 0 && tokens[i-1].IsLetter() && nextLetter(tokens, i-1) == i+1 {
+				continue // hyphen of a compound after a vowel: dharma-kṣetre
+			} else {
+				out.WriteString(token.Text)
+			}
+		}
+	}
+	return out.String()
+}
+
+// renderBrahmic - rewrites Devanagari in another Brahmic script. Unicode encodes these scripts in parallel
+// blocks, so a letter keeps its offset within the block and the script's font shapes the conjuncts of
+// consonant + virama sequences; letters the script lacks and word-final forms come from brahmicExceptions.
+// Dandas stay the shared Devanagari ones.
+func renderBrahmic(devanagari string, to Scheme) string {
+	runes := []rune(devanagari)
+	exceptions := brahmicExceptions[to]
+	finals := brahmicFinals[to]
+
+	var out bytes.Buffer
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if final, ok := finals[r]; ok && i+1 < len(runes) && runes[i+1] == virama {
+			// a dead consonant not followed by another one: कर्मणि keeps the conjunct, तत् ends in a final form
+			if _, conjunct := devaConsonants[nextRune(runes, i+2)]; !conjunct {
+				out.WriteRune(final)
+				i++
+				continue
+			}
+		}
+		if exception, ok := exceptions[r]; ok {
+			out.WriteString(exception)
+		} else if r >= devaBlock && r < devaBlock+blockSize && r != danda && r != doubleDanda {
+			out.WriteRune(r - devaBlock + brahmicBlocks[to])
+		} else {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// nextRune - runes[i], skipping zero width (non-)joiners; 0 past the end
+func nextRune(runes []rune, i int) rune {
+	for ; i < len(runes); i++ {
+		if runes[i] != zwj && runes[i] != zwnj {
+			return runes[i]
+		}
+	}
+	return 0
+}
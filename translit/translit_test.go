@@ -0,0 +1,121 @@
+package translit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// corpusFile - corpus whose Devanagari verse lines must survive the round trip
+const corpusFile = "../public/texts/lt/83.json"
+
+func TestDevanagariRoundTrip(t *testing.T) {
+	data, err := ioutil.ReadFile(corpusFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var book struct {
+		Chapters []struct {
+			Num    int
+			Verses []struct {
+				Num        int
+				Devanagari []string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &book); err != nil {
+		t.Fatal(err)
+	}
+
+	// joiners only affect the rendering and ॐ is written out as ओं
+	normalise := strings.NewReplacer("\u200c", "", "\u200d", "", "ॐ", "ओं")
+	verses, lines := 0, 0
+	for _, chapter := range book.Chapters {
+		for _, verse := range chapter.Verses {
+			verses++
+			for _, line := range verse.Devanagari {
+				lines++
+				iast := FromDevanagari(line, IAST)
+				if got, want := FromIAST(iast, Devanagari), normalise.Replace(line); got != want {
+					t.Errorf("%d.%d: %q -> %q -> %q", chapter.Num, verse.Num, line, iast, got)
+				}
+			}
+		}
+	}
+	if verses < 700 {
+		t.Errorf("%d verses in %s, expected 700", verses, corpusFile)
+	}
+	t.Logf("%d lines of %d verses", lines, verses)
+}
+
+func TestFromIAST(t *testing.T) {
+	tests := []struct {
+		iast   string
+		scheme Scheme
+		want   string
+	}{
+		{"kṛṣṇa", Devanagari, "कृष्ण"},
+		{"bhagavad-gītā", Devanagari, "भगवद्गीता"},
+		{"dharma-kṣetre", Devanagari, "धर्मक्षेत्रे"},
+		{"saṁjaya uvāca", Devanagari, "संजय उवाच"},
+		{"duḥkham", Devanagari, "दुःखम्"},
+
+		{"kṛṣṇa", HarvardKyoto, "kRSNa"},
+		{"bhagavad-gītā", HarvardKyoto, "bhagavad-gItA"},
+		{"jñānam", HarvardKyoto, "jJAnam"},
+		{"śrī", HarvardKyoto, "zrI"},
+		{"saṁjaya uvāca", HarvardKyoto, "saMjaya uvAca"},
+		{"duḥkham", HarvardKyoto, "duHkham"},
+		{"ṭhaḍḍha", HarvardKyoto, "ThaDDha"},
+
+		{"kṛṣṇa", ITRANS, "kRRiShNa"},
+		{"jñānam", ITRANS, "j~nAnam"},
+		{"śrī", ITRANS, "shrI"},
+		{"saṁjaya uvāca", ITRANS, "saMjaya uvAcha"},
+		{"aiśvarya", ITRANS, "aishvarya"},
+
+		{"kṛṣṇa", Velthuis, "k.r.s.na"},
+		{"bhagavad-gītā", Velthuis, "bhagavad-giitaa"},
+		{"jñānam", Velthuis, "j~naanam"},
+		{"śrī", Velthuis, `"srii`},
+		{"saṁjaya uvāca", Velthuis, "sa.mjaya uvaaca"},
+		{"duḥkham", Velthuis, "du.hkham"},
+		{"ṭhaḍḍha", Velthuis, ".tha.d.dha"},
+
+		{"kṛṣṇa", Simple, "krišna"},
+		{"bhagavad-gītā", Simple, "bhagavad-gita"},
+		{"jñānam", Simple, "džnanam"},
+		{"saṁjaya uvāca", Simple, "samdžaja uvača"},
+		{"aiśvarya", Simple, "aišvarja"},
+	}
+	for _, test := range tests {
+		if got := FromIAST(test.iast, test.scheme); got != test.want {
+			t.Errorf("FromIAST(%q, %s) = %q, want %q", test.iast, test.scheme, got, test.want)
+		}
+	}
+}
+
+func TestBrahmic(t *testing.T) {
+	tests := []struct {
+		scheme Scheme
+		want   map[string]string // by Devanagari
+	}{
+		{Bengali, map[string]string{"कृष्ण": "কৃষ্ণ", "धर्मक्षेत्रे": "ধর্মক্ষেত্রে", "संजय उवाच": "সংজয উবাচ", "दुःखम्": "দুঃখম্"}},
+		{Gujarati, map[string]string{"कृष्ण": "કૃષ્ણ", "धर्मक्षेत्रे": "ધર્મક્ષેત્રે", "संजय उवाच": "સંજય ઉવાચ", "दुःखम्": "દુઃખમ્"}},
+		{Oriya, map[string]string{"कृष्ण": "କୃଷ୍ଣ", "धर्मक्षेत्रे": "ଧର୍ମକ୍ଷେତ୍ରେ", "संजय उवाच": "ସଂଜଯ ଉଵାଚ", "दुःखम्": "ଦୁଃଖମ୍"}},
+		{Telugu, map[string]string{"कृष्ण": "కృష్ణ", "धर्मक्षेत्रे": "ధర్మక్షేత్రే", "संजय उवाच": "సంజయ ఉవాచ", "दुःखम्": "దుఃఖమ్"}},
+		{Kannada, map[string]string{"कृष्ण": "ಕೃಷ್ಣ", "धर्मक्षेत्रे": "ಧರ್ಮಕ್ಷೇತ್ರೇ", "संजय उवाच": "ಸಂಜಯ ಉವಾಚ", "दुःखम्": "ದುಃಖಮ್"}},
+		{Malayalam, map[string]string{"कृष्ण": "കൃഷ്ണ", "धर्मक्षेत्रे": "ധര്മക്ഷേത്രേ", "संजय उवाच": "സംജയ ഉവാച", "दुःखम्": "ദുഃഖമ്"}},
+	}
+	for _, test := range tests {
+		for devanagari, want := range test.want {
+			if got := FromDevanagari(devanagari, test.scheme); got != want {
+				t.Errorf("FromDevanagari(%q, %s) = %q, want %q", devanagari, test.scheme, got, want)
+			}
+			if got := FromIAST(FromDevanagari(devanagari, IAST), test.scheme); got != want {
+				t.Errorf("FromIAST(%q, %s) = %q, want %q", FromDevanagari(devanagari, IAST), test.scheme, got, want)
+			}
+		}
+	}
+}
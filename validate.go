@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// Violation - a single problem found in a corpus
+type Violation struct {
+	File     string `json:"file,omitempty"`
+	Location string `json:"location"` // chapter.verse, e.g. "2.13", or just chapter: "2"
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"` // "error" - breaks the site, "warning" - content inconsistency
+	Message  string `json:"message"`
+}
+
+// verseMarkerRe - chapter-verse number at the end of a Devanagari block: ॥१-१॥, ॥१८- ६५॥
+var verseMarkerRe = regexp.MustCompile(`॥\s*([०-९]+)\s*-\s*([०-९]+)\s*॥\s*$`)
+
+// devanagariAtoi - converts Devanagari digits to a number: १८ -> 18
+func devanagariAtoi(s string) int {
+	n := 0
+	for _, r := range s {
+		n = n*10 + int(r-'०')
+	}
+	return n
+}
+
+// validateBook - checks the book for inconsistencies the site depends on
+func validateBook(book *Book) []Violation {
+	var violations []Violation
+	report := func(location, rule, severity, format string, args ...interface{}) {
+		violations = append(violations, Violation{Location: location, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for chapterIdx, chapter := range book.Chapters {
+		if chapter.Num != chapterIdx+1 {
+			report(strconv.Itoa(chapterIdx+1), "chapter-num", "error", "chapter number %d, expected %d", chapter.Num, chapterIdx+1)
+		}
+
+		for verseIdx, verse := range chapter.Verses {
+			location := fmt.Sprintf("%d.%d", chapterIdx+1, verseIdx+1)
+
+			if verse.Num != verseIdx+1 {
+				report(location, "verse-num", "error", "verse number %d, expected %d", verse.Num, verseIdx+1)
+			}
+
+			if len(verse.SynonymsSanskrit) != len(verse.SynonymsTranslation) {
+				report(location, "synonyms-length", "error", "%d Sanskrit synonyms, %d translations", len(verse.SynonymsSanskrit), len(verse.SynonymsTranslation))
+			}
+
+			// Devanagari must survive the transliteration to IAST and back
+			for _, line := range verse.Devanagari {
+				want := strings.NewReplacer("\u200c", "", "\u200d", "", "ॐ", "ओं").Replace(line)
+				if roundTrip := translit.FromIAST(translit.FromDevanagari(line, translit.IAST), translit.Devanagari); roundTrip != want {
+					report(location, "devanagari-roundtrip", "warning", "%q transliterates back as %q", line, roundTrip)
+				}
+			}
+
+			if len(verse.Devanagari) == 0 {
+				report(location, "devanagari-marker", "warning", "no Devanagari text")
+				continue
+			}
+			lastLine := verse.Devanagari[len(verse.Devanagari)-1]
+			marker := verseMarkerRe.FindStringSubmatch(lastLine)
+			if marker == nil {
+				report(location, "devanagari-marker", "warning", "no ॥chapter-verse॥ marker at the end of %q", lastLine)
+			} else if devanagariAtoi(marker[1]) != chapterIdx+1 || devanagariAtoi(marker[2]) != verseIdx+1 {
+				report(location, "devanagari-marker", "warning", "marker %s does not match the verse", strings.TrimSpace(marker[0]))
+			}
+		}
+	}
+	return violations
+}
+
+// validateCommand - "validate [file.json...]" subcommand: prints violations as JSON lines.
+// Exit code is 0 if the corpora are valid, 1 if there are violations, 2 if a file cannot be loaded.
+func validateCommand(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s validate [file.json...]\nWithout files validates all corpora and the recitations in %s\n", os.Args[0], textsDir)
+	}
+	flags.Parse(args)
+
+	files := flags.Args()
+	if len(files) == 0 {
+		corpusFiles, err := discoverCorpora(textsDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, file := range corpusFiles {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+	}
+
+	exitCode := 0
+	out := json.NewEncoder(os.Stdout)
+	report := func(file string, violations []Violation) {
+		for _, violation := range violations {
+			violation.File = file
+			out.Encode(violation)
+			if exitCode == 0 {
+				exitCode = 1
+			}
+		}
+	}
+	var firstBook *Book
+	for _, file := range files {
+		book, err := loadBook(file)
+		if err != nil {
+			out.Encode(Violation{File: file, Rule: "load", Severity: "error", Message: err.Error()})
+			exitCode = 2
+			continue
+		}
+		if firstBook == nil {
+			firstBook = book
+		}
+		report(file, validateBook(book))
+	}
+
+	// the recitation timings belong to the Sanskrit text, which is the same in every corpus
+	if len(flags.Args()) == 0 && firstBook != nil {
+		catalogue, err := loadCatalogue(catalogueFile())
+		if err != nil {
+			out.Encode(Violation{File: catalogueFile(), Rule: "load", Severity: "error", Message: err.Error()})
+			return 2
+		}
+		report(catalogueFile(), validateCatalogue(catalogue, firstBook))
+	}
+	return exitCode
+}
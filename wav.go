@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// Recording - a recording as mono samples between -1 and 1
+type Recording struct {
+	SampleRate int
+	Samples    []float64
+}
+
+// Duration - length of the recording in seconds
+func (rec *Recording) Duration() float64 {
+	return float64(len(rec.Samples)) / float64(rec.SampleRate)
+}
+
+// WAV format tags of the "fmt " chunk
+const (
+	wavPCM        = 1
+	wavFloat      = 3
+	wavExtensible = 0xfffe // the actual format tag follows in the extension
+)
+
+// readWAV - reads a RIFF WAVE file with 8, 16, 24 or 32 bit PCM or 32, 64 bit float samples; channels are mixed down
+func readWAV(filename string) (*Recording, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%s is not a WAV file", filename)
+	}
+
+	var format, channels, bits int
+	var sampleRate int
+	var samples []byte
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			size = len(body) // data chunk of a recording cut short
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("%s: fmt chunk of %d bytes", filename, size)
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == wavExtensible && size >= 26 {
+				format = int(binary.LittleEndian.Uint16(body[24:26]))
+			}
+		case "data":
+			samples = body[:size]
+		}
+		pos += 8 + size + size%2 // chunks are padded to an even size
+	}
+	if channels == 0 || sampleRate == 0 {
+		return nil, fmt.Errorf("%s: no fmt chunk", filename)
+	}
+
+	bytesPerSample := bits / 8
+	decode := sampleDecoder(format, bits)
+	if decode == nil {
+		return nil, fmt.Errorf("%s: unsupported format %d with %d bit samples", filename, format, bits)
+	}
+	frameSize := channels * bytesPerSample
+	recording := &Recording{SampleRate: sampleRate, Samples: make([]float64, 0, len(samples)/frameSize)}
+	for pos := 0; pos+frameSize <= len(samples); pos += frameSize {
+		sum := 0.0
+		for channel := 0; channel < channels; channel++ {
+			offset := pos + channel*bytesPerSample
+			sum += decode(samples[offset : offset+bytesPerSample])
+		}
+		recording.Samples = append(recording.Samples, sum/float64(channels))
+	}
+	return recording, nil
+}
+
+// sampleDecoder - converts a little endian sample to -1..1; nil for an unsupported format
+func sampleDecoder(format, bits int) func([]byte) float64 {
+	switch {
+	case format == wavPCM && bits == 8:
+		return func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == wavPCM && bits == 16:
+		return func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case format == wavPCM && bits == 24:
+		return func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case format == wavPCM && bits == 32:
+		return func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case format == wavFloat && bits == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case format == wavFloat && bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/bhakterija/bhagavad-gita.lt/translit"
+)
+
+// Word - a word of a verse line as highlighted during the recitation
+type Word struct {
+	Index     int    // position among the words of all lines of the verse, as in its word timings; -1 for the verse number marker ॥१-१॥
+	Text      string // as stored, or transliterated for display
+	Start     float32
+	End       float32
+	Timed     bool // Start and End are recorded: the recitation has a timing for every word
+	Estimated bool // Start and End are estimated fractions of the recitation, to be scaled by its duration
+}
+
+// verseLineWords - splits verse lines into words: on whitespace, with the danda "।" a word of its own and
+// the verse number marker a word without an index
+func verseLineWords(lines []string) [][]Word {
+	var words [][]Word
+	index := 0
+	for _, line := range lines {
+		marker := verseMarkerRe.FindString(line)
+		var lineWords []Word
+		for _, text := range strings.Fields(strings.TrimSuffix(line, marker)) {
+			if text != "।" && strings.HasSuffix(text, "।") {
+				lineWords = append(lineWords, Word{Index: index, Text: strings.TrimSuffix(text, "।")})
+				index++
+				text = "।"
+			}
+			lineWords = append(lineWords, Word{Index: index, Text: text})
+			index++
+		}
+		if marker = strings.TrimSpace(marker); marker != "" {
+			lineWords = append(lineWords, Word{Index: -1, Text: marker})
+		}
+		words = append(words, lineWords)
+	}
+	return words
+}
+
+// timedGroupWords - words of the verses of a joined group for the track, numbered through the group as by groupWords
+// and split back into the lines of every verse, with their timings. timings returns the recorded timings of a verse,
+// in seconds of the group's recording, or is nil for words without timings. Timings hold a start time for every word
+// of the verse plus the end time of its last word; they are only attached if every verse has ones matching its words,
+// otherwise words would be highlighted against the timing of their neighbours and the words of the whole group get
+// timings estimated over its recording instead.
+func timedGroupWords(verses []Verse, track TimingTrack, timings func(Verse) []float32) [][][]Word {
+	words, offsets := groupWords(verses, track.Words)
+	recorded := timings != nil
+	for _, verse := range verses {
+		recorded = recorded && len(timings(verse)) == wordCount(track.Words(verse))+1
+	}
+
+	grouped := make([][][]Word, len(verses))
+	line := 0
+	for v, verse := range verses {
+		lineCount := len(track.Words(verse))
+		grouped[v] = words[line : line+lineCount]
+		line += lineCount
+		if recorded {
+			// the timings of the verse are indexed from its first word
+			attachTimings(grouped[v], append(make([]float32, offsets[v]), timings(verse)...), false)
+		}
+	}
+	if !recorded && timings != nil {
+		if estimated := estimateTimings(words, track.Parse, 1); estimated != nil {
+			attachTimings(words, estimated, true)
+		}
+	}
+	return grouped
+}
+
+// attachTimings - sets Start and End of the indexed words from timings with one more entry than words
+func attachTimings(words [][]Word, timings []float32, estimated bool) {
+	for _, lineWords := range words {
+		for i, word := range lineWords {
+			if word.Index >= 0 {
+				lineWords[i].Start, lineWords[i].End = timings[word.Index], timings[word.Index+1]
+				lineWords[i].Timed, lineWords[i].Estimated = !estimated, estimated
+			}
+		}
+	}
+}
+
+// wordCount - number of indexed words in lines split by verseLineWords
+func wordCount(words [][]Word) int {
+	count := 0
+	for _, lineWords := range words {
+		for _, word := range lineWords {
+			if word.Index >= 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// transliterateWords - copy of the words with the text converted to the scheme
+func transliterateWords(words [][]Word, convert func(string, translit.Scheme) string, to translit.Scheme) [][]Word {
+	converted := make([][]Word, len(words))
+	for i, lineWords := range words {
+		converted[i] = make([]Word, len(lineWords))
+		for j, word := range lineWords {
+			word.Text = convert(word.Text, to)
+			converted[i][j] = word
+		}
+	}
+	return converted
+}